@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"aiterm/internal/cache"
+	"aiterm/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local response cache",
+	Long: `Repeated requests are answered from ~/.aiterm/cache without asking the
+model. An answer is reused when the prompt (ignoring extra whitespace),
+target OS and shell, endpoints and models, and everything else sent with
+it (prompt template, examples, environment context) are the same.
+
+Answers expire after cache_ttl; beyond cache_max_entries the least
+recently used are evicted. Pass --no-cache to ask the model anyway, or
+turn the cache off with 'aiterm config set cache false'.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached answers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, store, err := openCache()
+		if err != nil {
+			return err
+		}
+		n, err := store.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d cached answers.\n", n)
+		return nil
+	},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the number, size and hits of cached answers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, store, err := openCache()
+		if err != nil {
+			return err
+		}
+		st, err := store.Stats()
+		if err != nil {
+			return err
+		}
+		dir, _ := cache.Dir()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Directory:\t%s\n", dir)
+		fmt.Fprintf(w, "Enabled:\t%t\n", cfg.Cache)
+		fmt.Fprintf(w, "Entries:\t%d of %d (%d expired)\n", st.Entries, cfg.CacheMaxEntries, st.Expired)
+		fmt.Fprintf(w, "Size:\t%s\n", formatBytes(st.Bytes))
+		fmt.Fprintf(w, "Hits:\t%d\n", st.Hits)
+		fmt.Fprintf(w, "TTL:\t%s\n", cfg.CacheTTLDuration())
+		if st.Entries > 0 {
+			fmt.Fprintf(w, "Oldest:\t%s\n", st.Oldest.Format("2006-01-02 15:04"))
+			fmt.Fprintf(w, "Newest:\t%s\n", st.Newest.Format("2006-01-02 15:04"))
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// openCache opens the cache store with the configured policy.
+func openCache() (*config.Config, *cache.Store, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	store, err := cache.Open(cfg.CacheTTLDuration(), cfg.CacheMaxEntries)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, store, nil
+}
+
+// formatBytes renders a size such as "12.3 KiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
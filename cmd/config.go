@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"aiterm/internal/config"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var configCmd = &cobra.Command{
@@ -47,16 +51,28 @@ var configGetCmd = &cobra.Command{
 	},
 }
 
+var configSetForce bool
+
 var configSetCmd = &cobra.Command{
 	Use:   "set <key> <value>",
 	Short: "Set a configuration value",
-	Args:  cobra.ExactArgs(2),
+	Long: `Sets a configuration value and saves it.
+
+A new model is checked against the models the endpoint lists (see
+'aiterm models'); --force skips the check.`,
+	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
+		if strings.EqualFold(args[0], "model") && !configSetForce {
+			if err := checkModel(cfg, args[1]); err != nil {
+				return silenceExitError(cmd, err)
+			}
+		}
+
 		if err := cfg.Set(args[0], args[1]); err != nil {
 			return err
 		}
@@ -66,8 +82,114 @@ var configSetCmd = &cobra.Command{
 	},
 }
 
+var (
+	endpointToken    string
+	endpointProvider string
+)
+
+var configEndpointsCmd = &cobra.Command{
+	Use:   "endpoints",
+	Short: "List the failover endpoints",
+	Long: `List the endpoints tried in order when the primary api_endpoint fails
+with a connection error, a server error or an authentication failure.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		for i, ep := range cfg.EndpointChain() {
+			label := "fallback"
+			if i == 0 {
+				label = "primary"
+			}
+			provider := ep.Provider
+			if provider == "" {
+				provider = config.ProviderOpenAI
+			}
+			fmt.Printf("%d. [%s] %s  model=%s  provider=%s  token=%s\n",
+				i, label, ep.APIEndpoint, ep.Model, provider, config.MaskToken(ep.APIToken))
+		}
+		return nil
+	},
+}
+
+var configEndpointsAddCmd = &cobra.Command{
+	Use:   "add <api_endpoint> <model>",
+	Short: "Append a failover endpoint",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		token := endpointToken
+		if token == "" && !strings.EqualFold(endpointProvider, config.ProviderOllama) {
+			fmt.Print("API Token: ")
+			tokenBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println()
+			if err != nil {
+				return fmt.Errorf("failed to read token (use --token): %w", err)
+			}
+			token = strings.TrimSpace(string(tokenBytes))
+		}
+
+		ep := config.Endpoint{
+			APIEndpoint: args[0],
+			APIToken:    token,
+			Model:       args[1],
+			Provider:    strings.ToLower(endpointProvider),
+		}
+		if err := ep.Validate(); err != nil {
+			return err
+		}
+
+		cfg.Endpoints = append(cfg.Endpoints, ep)
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Added fallback endpoint %d\n", len(cfg.Endpoints))
+		return nil
+	},
+}
+
+var configEndpointsRemoveCmd = &cobra.Command{
+	Use:   "remove <number>",
+	Short: "Remove a failover endpoint by its number in 'config endpoints'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(cfg.Endpoints) {
+			return fmt.Errorf("no fallback endpoint %s — run 'aiterm config endpoints' to list them", args[0])
+		}
+
+		cfg.Endpoints = append(cfg.Endpoints[:n-1], cfg.Endpoints[n:]...)
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed fallback endpoint %d\n", n)
+		return nil
+	},
+}
+
 func init() {
+	configSetCmd.Flags().BoolVar(&configSetForce, "force", false, "Set the model even if the endpoint doesn't list it")
+	configEndpointsAddCmd.Flags().StringVar(&endpointToken, "token", "", "API token for the endpoint (prompted if omitted)")
+	configEndpointsAddCmd.Flags().StringVar(&endpointProvider, "provider", "", "Provider for the endpoint (default openai)")
+	configEndpointsCmd.AddCommand(configEndpointsAddCmd)
+	configEndpointsCmd.AddCommand(configEndpointsRemoveCmd)
+
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configEndpointsCmd)
 	rootCmd.AddCommand(configCmd)
 }
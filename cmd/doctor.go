@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"aiterm/internal/ai"
+	"aiterm/internal/config"
+	"aiterm/internal/transport"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorNoConnect bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration, network settings and connection",
+	Long: `Shows which endpoints are configured and which transport settings are in
+effect for them: proxy (from transport.proxy or HTTPS_PROXY / NO_PROXY),
+extra CA bundles, the client certificate for mutual TLS, SPKI pins and
+extra headers. Then tests the connection to the primary endpoint.
+
+Transport settings:
+  aiterm config set transport.proxy http://proxy.corp:3128
+  aiterm config set transport.proxy none
+  aiterm config set transport.ca_bundles /etc/ssl/corp-root.pem
+  aiterm config set transport.client_cert ~/.certs/me.pem
+  aiterm config set transport.client_key ~/.certs/me.key
+  aiterm config set transport.pin_spki sha256/AAAA...=
+  aiterm config set header.X-Team platform`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		path, _ := config.ConfigFilePath()
+		problems := 0
+
+		fmt.Printf("Config: %s\n\n", path)
+
+		fmt.Println("Endpoints:")
+		for i, ep := range cfg.EndpointChain() {
+			role := "primary"
+			if i > 0 {
+				role = fmt.Sprintf("fallback %d", i)
+			}
+			fmt.Printf("  %-10s  %s\n", role, ep.Name())
+		}
+		if err := cfg.Validate(); err != nil {
+			fmt.Printf("  \033[33m✗ %v\033[0m\n", err)
+			problems++
+		}
+
+		fmt.Println("\nTransport:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, s := range transport.Describe(cfg, cfg.APIEndpoint) {
+			fmt.Fprintf(w, "  %s\t%s\n", s.Name, s.Value)
+			if s.Err != nil {
+				fmt.Fprintf(w, "  \t\033[33m✗ %v\033[0m\n", s.Err)
+				problems++
+			}
+		}
+		w.Flush()
+
+		if !doctorNoConnect && problems == 0 {
+			fmt.Print("\nConnection: ")
+			ctx, cancel := apiContext(cfg)
+			defer cancel()
+
+			client := ai.NewClient(cfg)
+			start := time.Now()
+			if err := client.TestConnection(ctx); errors.Is(err, context.Canceled) {
+				fmt.Println()
+				return silenceExitError(cmd, apiFailure(client, "connection test", err))
+			} else if err != nil {
+				fmt.Printf("\033[33m✗ %v\033[0m\n", err)
+				problems++
+			} else {
+				fmt.Printf("✓ %s answered in %s\n", cfg.APIEndpoint, time.Since(start).Round(time.Millisecond))
+			}
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("found %d problem(s)", problems)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorNoConnect, "no-connect", false, "Only check the settings, don't contact the endpoint")
+	rootCmd.AddCommand(doctorCmd)
+}
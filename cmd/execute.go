@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"aiterm/internal/config"
+	"aiterm/internal/shell"
+)
+
+// exitError carries the exit status of an executed command back to Execute
+// so that aiterm exits with the same code.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.code)
+}
+
+// execShell returns the shell confirmed commands run in: the one they were
+// generated for, keeping the configured path (such as /opt/bin/fish) when it
+// names that same shell.
+func execShell(cfg *config.Config, shellType string) string {
+	configured, ok := shell.Lookup(cfg.Shell)
+	if target, _ := shell.Lookup(shellType); ok && configured.ID == target.ID {
+		return cfg.Shell
+	}
+	return shellType
+}
+
+// stdinReader is shared by all prompts so that buffered input is not lost
+// between them.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// pickCommand shows a numbered list of candidates on stderr and returns the
+// one the user picked, or "" if they quit.
+func pickCommand(commands []string) (string, error) {
+	fmt.Fprintln(os.Stderr)
+	for i, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %d) \033[1m%s\033[0m\n", i+1, c)
+	}
+
+	for {
+		fmt.Fprintf(os.Stderr, "\nPick a command [1-%d, q to quit] (1): ", len(commands))
+
+		answer, err := stdinReader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read answer: %w", err)
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		switch {
+		case answer == "" && err == io.EOF:
+			fmt.Fprintln(os.Stderr, "\nCancelled.")
+			return "", nil
+		case answer == "":
+			return commands[0], nil
+		case answer == "q" || answer == "quit":
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return "", nil
+		}
+
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(commands) {
+			return commands[n-1], nil
+		}
+		fmt.Fprintf(os.Stderr, "Please enter a number between 1 and %d.\n", len(commands))
+	}
+}
+
+// confirmAndRun shows the command on stderr, asks whether to run, edit or
+// skip it, and executes it through shellName. When assumeYes is set the
+// command runs without asking.
+func confirmAndRun(ctx context.Context, command, shellName string, assumeYes bool) error {
+	for !assumeYes {
+		fmt.Fprintf(os.Stderr, "\n  \033[1m%s\033[0m\n\n", command)
+		fmt.Fprint(os.Stderr, "Run this command? [Y/n/e(dit)] ")
+
+		answer, err := stdinReader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read answer: %w", err)
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		switch {
+		case answer == "" && err == io.EOF:
+			// Ctrl-D or a closed stdin never runs anything.
+			fmt.Fprintln(os.Stderr, "\nCancelled.")
+			return nil
+		case answer == "", answer == "y", answer == "yes":
+			assumeYes = true
+		case answer == "n", answer == "no":
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return nil
+		case answer == "e", answer == "edit":
+			fmt.Fprint(os.Stderr, "Edit command (empty keeps current): ")
+			edited, _ := stdinReader.ReadString('\n')
+			if edited = strings.TrimSpace(edited); edited != "" {
+				command = edited
+			}
+		case err == io.EOF:
+			fmt.Fprintln(os.Stderr, "\nCancelled.")
+			return nil
+		default:
+			fmt.Fprintln(os.Stderr, "Please answer y, n or e.")
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\033[90m$ %s\033[0m\n", command)
+
+	code, err := shell.Run(ctx, shellName, command, shell.Streams{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &exitError{code: code}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"aiterm/internal/ai"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainTarget string
+	explainJSON   bool
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [command]",
+	Short: "Explain what an existing command does",
+	Long: `Sends an existing command to the AI and prints a per-segment breakdown of
+its programs, flags, pipes and redirections, an overall summary and any
+dangerous side effects. The command is read from stdin when omitted or "-".
+
+Examples:
+  aiterm explain "find . -name '*.log' -mtime +7 -delete"
+  pbpaste | aiterm explain
+  aiterm explain -t win "Get-ChildItem -Recurse | Remove-Item"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		command := strings.Join(args, " ")
+		if command == "" || command == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read command from stdin: %w", err)
+			}
+			command = string(data)
+		}
+		command = strings.TrimSpace(command)
+		if command == "" {
+			return fmt.Errorf("no command to explain — pass it as an argument or on stdin")
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+
+		client := ai.NewClient(cfg)
+		ctx, cancel := apiContext(cfg)
+		defer cancel()
+
+		exp, err := client.ExplainCommand(ctx, command, explainTarget)
+		if err != nil {
+			return silenceExitError(cmd, apiFailure(client, "explanation", err))
+		}
+		reportEndpoint(cfg, client)
+		reportUsage(cfg, client)
+
+		if explainJSON {
+			return printJSON(exp)
+		}
+		printExplanation(exp)
+		return nil
+	},
+}
+
+func init() {
+	explainCmd.Flags().StringVarP(&explainTarget, "type", "t", "", "Target OS type: win, linux, linux/<distro>, mac (auto-detected if omitted)")
+	explainCmd.Flags().BoolVar(&explainJSON, "json", false, "Print the explanation as JSON")
+	rootCmd.AddCommand(explainCmd)
+}
+
+// printExplanation renders an explanation for the terminal.
+func printExplanation(exp *ai.Explanation) {
+	fmt.Printf("\033[1mSummary\033[0m\n  %s\n", exp.Summary)
+
+	if len(exp.Segments) > 0 {
+		width := 0
+		for _, seg := range exp.Segments {
+			if len(seg.Text) > width {
+				width = len(seg.Text)
+			}
+		}
+		if width > 40 {
+			width = 40
+		}
+
+		fmt.Printf("\n\033[1mBreakdown\033[0m\n")
+		for _, seg := range exp.Segments {
+			fmt.Printf("  \033[36m%-*s\033[0m  %s\n", width, seg.Text, seg.Explanation)
+		}
+	}
+
+	fmt.Printf("\n\033[1mRisk\033[0m\n  %s\n", colorRisk(exp.RiskLevel))
+
+	if len(exp.SideEffects) > 0 {
+		fmt.Printf("\n\033[1;31mSide effects\033[0m\n")
+		for _, effect := range exp.SideEffects {
+			fmt.Printf("  - %s\n", effect)
+		}
+	}
+}
+
+// colorRisk colors a risk level by severity.
+func colorRisk(level string) string {
+	switch level {
+	case ai.RiskSafe, ai.RiskLow:
+		return "\033[32m" + level + "\033[0m"
+	case ai.RiskMedium:
+		return "\033[33m" + level + "\033[0m"
+	case ai.RiskHigh, ai.RiskCritical:
+		return "\033[31m" + level + "\033[0m"
+	}
+	return level
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"aiterm/internal/ai"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	fixTarget     string
+	fixExitCode   int
+	fixStderr     string
+	fixStderrFile string
+	fixHook       string
+	fixPrintOnly  bool
+)
+
+// fixHooks holds shell functions that pass the last history entry and its
+// exit status to 'aiterm fix'.
+var fixHooks = map[string]string{
+	"bash": `# aiterm fix hook — add to ~/.bashrc, then run 'aifix' after a command fails
+aifix() {
+  local status=$?
+  aiterm fix --exit-code "$status" -- "$(fc -ln -1)"
+}
+`,
+	"zsh": `# aiterm fix hook — add to ~/.zshrc, then run 'aifix' after a command fails
+aifix() {
+  local exit_status=$?
+  aiterm fix --exit-code "$exit_status" -- "$(fc -ln -1)"
+}
+`,
+	"fish": `# aiterm fix hook — add to ~/.config/fish/config.fish, then run 'aifix' after a command fails
+function aifix
+    set -l exit_status $status
+    aiterm fix --exit-code $exit_status -- $history[1]
+end
+`,
+}
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [flags] -- <failed command>",
+	Short: "Repair a failed command from its exit code and error output",
+	Long: `Sends a failed command, its exit code and its captured stderr to the AI and
+suggests a corrected command with a one-line reason. The suggestion is never
+the command that already failed.
+
+Error output is taken from --stderr, --stderr-file, or stdin when it is piped.
+
+Examples:
+  aiterm fix -e 1 --stderr "sed: 1: invalid command code" -- "sed -i 's/a/b/' f.txt"
+  make 2>&1 >/dev/null | aiterm fix -e 2 -- make
+  eval "$(aiterm fix --hook bash)"   # defines 'aifix' for the last command`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if fixHook != "" {
+			hook, ok := fixHooks[strings.ToLower(fixHook)]
+			if !ok {
+				return fmt.Errorf("no hook for shell %q — supported: bash, zsh, fish", fixHook)
+			}
+			fmt.Print(hook)
+			return nil
+		}
+
+		command := strings.TrimSpace(strings.Join(args, " "))
+		if command == "" {
+			return fmt.Errorf("no command to fix — pass it after --")
+		}
+
+		stderr, err := readFixStderr()
+		if err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+
+		osName, shellType := ai.ResolveTarget(fixTarget, cfg.Shell)
+		fmt.Fprintf(os.Stderr, "\033[90m[%s / %s] Fixing...\033[0m\n", targetLabel(fixTarget, osName), shellType)
+
+		client := ai.NewClient(cfg)
+		ctx, cancel := apiContext(cfg)
+		defer cancel()
+
+		fix, err := client.FixCommand(ctx, ai.FailedCommand{
+			Command:  command,
+			ExitCode: fixExitCode,
+			Stderr:   stderr,
+		}, fixTarget)
+		if err != nil {
+			return silenceExitError(cmd, apiFailure(client, "fix", err))
+		}
+		reportEndpoint(cfg, client)
+		reportUsage(cfg, client)
+		warnMissingTools([]string{fix.Command}, fixTarget, shellType)
+
+		if fix.Reason != "" {
+			fmt.Fprintf(os.Stderr, "\033[90m%s\033[0m\n", fix.Reason)
+		}
+
+		if fixPrintOnly || !ai.IsLocalTarget(fixTarget) || !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Println(fix.Command)
+			return nil
+		}
+
+		return silenceExitError(cmd, confirmAndRun(context.Background(), fix.Command, execShell(cfg, shellType), false))
+	},
+}
+
+func init() {
+	fixCmd.Flags().StringVarP(&fixTarget, "type", "t", "", "Target OS type: win, linux, linux/<distro>, mac (auto-detected if omitted)")
+	fixCmd.Flags().IntVarP(&fixExitCode, "exit-code", "e", 1, "Exit code of the failed command")
+	fixCmd.Flags().StringVar(&fixStderr, "stderr", "", "Captured error output of the failed command")
+	fixCmd.Flags().StringVar(&fixStderrFile, "stderr-file", "", "File containing the error output of the failed command")
+	fixCmd.Flags().StringVar(&fixHook, "hook", "", "Print a shell function that fixes the last command (bash, zsh, fish)")
+	fixCmd.Flags().BoolVarP(&fixPrintOnly, "print", "p", false, "Only print the corrected command, never run it")
+	fixCmd.MarkFlagsMutuallyExclusive("stderr", "stderr-file")
+	rootCmd.AddCommand(fixCmd)
+}
+
+// readFixStderr returns the failed command's error output from the flags
+// or, when stdin is piped, from stdin.
+func readFixStderr() (string, error) {
+	switch {
+	case fixStderr != "":
+		return fixStderr, nil
+	case fixStderrFile != "":
+		data, err := os.ReadFile(fixStderrFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stderr file: %w", err)
+		}
+		return string(data), nil
+	case !term.IsTerminal(int(os.Stdin.Fd())):
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stderr from stdin: %w", err)
+		}
+		return string(data), nil
+	}
+	return "", nil
+}
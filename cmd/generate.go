@@ -1,48 +1,120 @@
 package cmd
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
-	"time"
 
 	"aiterm/internal/ai"
-	"aiterm/internal/config"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	generateAlternatives int
+	nulSeparated         bool
+	jsonOutput           bool
+)
+
 var generateCmd = &cobra.Command{
 	Use:   "generate <description>",
 	Short: "Generate a command from a natural language description (headless mode)",
-	Long:  `Generates a shell command based on the given description and prints it to stdout. Useful for scripting and piping.`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Generates a shell command based on the given description and prints it to stdout. Useful for scripting and piping.
+
+With --alternatives N, up to N distinct candidates are printed one per line
+(or NUL-separated with -0), ready to be piped into a picker such as fzf:
+
+  aiterm generate -n 3 "find go files" | fzf
+
+With --json, a single object with the command, an explanation, its risk
+level, whether it needs sudo, the model's assumptions and the token usage
+is printed.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.Load()
+		cfg, err := loadConfig()
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+			return err
 		}
 
-		if err := cfg.Validate(); err != nil {
+		if jsonOutput && offlineOnly {
+			return fmt.Errorf("--json needs the API — drop --offline")
+		}
+		if err := cfg.Validate(); err != nil && !offlineOnly {
 			return err
 		}
 
 		description := strings.Join(args, " ")
 		client := ai.NewClient(cfg)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := apiContext(cfg)
 		defer cancel()
 
-		command, err := client.GenerateCommand(ctx, description, "")
+		if jsonOutput {
+			result, err := client.GenerateStructured(ctx, description, "")
+			if err != nil {
+				return silenceExitError(cmd, apiFailure(client, "generation", err))
+			}
+			reportEndpoint(cfg, client)
+			reportUsage(cfg, client)
+			if result.Unstructured {
+				fmt.Fprintln(os.Stderr, "\033[33mwarning: the model did not return structured output; only the command is set\033[0m")
+			}
+			_, shellType := ai.ResolveTarget("", cfg.Shell)
+			warnMissingTools([]string{result.Command}, "", shellType)
+
+			out := struct {
+				*ai.CommandResult
+				Usage *ai.Usage `json:"usage,omitempty"`
+			}{CommandResult: result}
+			if u, ok := client.Usage(); ok {
+				out.Usage = &u
+			}
+			return printJSON(out)
+		}
+
+		var commands []string
+		if offlineOnly {
+			commands, err = client.SuggestOffline(description, "", generateAlternatives)
+		} else {
+			commands, err = client.GenerateAlternatives(ctx, description, "", generateAlternatives)
+		}
 		if err != nil {
-			return fmt.Errorf("generation failed: %w", err)
+			return silenceExitError(cmd, apiFailure(client, "generation", err))
 		}
+		reportEndpoint(cfg, client)
+		reportCached(client)
+		reportOffline(client)
+		reportDiscarded(client)
+		reportUsage(cfg, client)
+		_, shellType := ai.ResolveTarget("", cfg.Shell)
+		warnMissingTools(commands, "", shellType)
+		saveSession(client, commands[0])
 
-		fmt.Println(command)
+		sep := "\n"
+		if nulSeparated {
+			sep = "\x00"
+		}
+		printCommands(commands, sep)
 		return nil
 	},
 }
 
 func init() {
+	generateCmd.Flags().IntVarP(&generateAlternatives, "alternatives", "n", 1, "Print up to N distinct candidate commands")
+	generateCmd.Flags().BoolVarP(&nulSeparated, "null", "0", false, "Separate candidates with NUL instead of newline")
+	generateCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print a JSON object with command, explanation, risk_level, requires_sudo and assumptions")
+	generateCmd.MarkFlagsMutuallyExclusive("json", "alternatives")
+	generateCmd.MarkFlagsMutuallyExclusive("json", "null")
 	rootCmd.AddCommand(generateCmd)
 }
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
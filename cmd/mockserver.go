@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"aiterm/internal/mockserver"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockAddr    string
+	mockRules   string
+	mockLatency time.Duration
+	mockToken   string
+)
+
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Serve canned completions for development and testing",
+	Long: `Runs an OpenAI-compatible server that answers /v1/chat/completions
+(streamed or not) and /v1/models from a rules file, without any model or
+network access. Point aiterm at it to try out generation, setup and error
+handling locally.
+
+Rules map regular expressions, searched in the last user message, to
+commands. They can also delay replies, answer with HTTP errors or send
+malformed JSON:
+
+  token: sk-mock              # required bearer token (optional)
+  models: [mock-model]
+  latency: 200ms              # delay for every reply
+  default: echo no rule       # reply when nothing matches
+  rules:
+    - match: (?i)disk space
+      command: df -h
+      alternatives: [du -sh .]
+    - match: (?i)flaky
+      status: 503
+      times: 2                # fail twice, then fall through
+    - match: (?i)broken
+      malformed: true
+
+Without --rules, every request is answered with an echo of the prompt.
+
+Examples:
+  aiterm mock-server
+  aiterm mock-server --rules mock.yaml --addr 127.0.0.1:9000
+  aiterm config set api_endpoint http://127.0.0.1:8080/v1/chat/completions`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules := mockserver.Default()
+		if mockRules != "" {
+			var err error
+			if rules, err = mockserver.Load(mockRules); err != nil {
+				return err
+			}
+		}
+		if cmd.Flags().Changed("latency") {
+			if mockLatency < 0 {
+				return fmt.Errorf("--latency must not be negative")
+			}
+			rules.SetLatency(mockLatency)
+		}
+		if mockToken != "" {
+			rules.Token = mockToken
+		}
+
+		ln, err := net.Listen("tcp", mockAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Mock server listening on http://%s/v1/chat/completions\n", ln.Addr())
+		fmt.Fprintf(os.Stderr, "\033[90mUse it with: aiterm config set api_endpoint http://%s/v1/chat/completions\033[0m\n", ln.Addr())
+		return http.Serve(ln, mockserver.New(rules, os.Stderr).Handler())
+	},
+}
+
+func init() {
+	mockServerCmd.Flags().StringVar(&mockAddr, "addr", "127.0.0.1:8080", "Address to listen on")
+	mockServerCmd.Flags().StringVar(&mockRules, "rules", "", "Rules file (.yaml, .yml or .json)")
+	mockServerCmd.Flags().DurationVar(&mockLatency, "latency", 0, "Delay replies by this long, overriding the latency of the rules file")
+	mockServerCmd.Flags().StringVar(&mockToken, "token", "", "Require this bearer token, overriding the rules file")
+	rootCmd.AddCommand(mockServerCmd)
+}
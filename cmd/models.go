@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"aiterm/internal/ai"
+	"aiterm/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var modelsJSON bool
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the models offered by the endpoint",
+	Long: `Lists the models the primary endpoint offers to your token, with their
+owner and context length where the endpoint reports them. The configured
+model is marked with *.
+
+The list comes from /v1/models next to api_endpoint for OpenAI-compatible
+endpoints (OpenAI, LiteLLM, vLLM, ...) and Anthropic, from /api/tags for
+Ollama and from /models for Gemini. For LiteLLM these are the model_name
+values of its model_list.
+
+Examples:
+  aiterm models
+  aiterm config set model "$(aiterm models --json | jq -r '.[0].id')"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+
+		client := ai.NewClient(cfg)
+		ctx, cancel := apiContext(cfg)
+		defer cancel()
+
+		models, err := client.ListModels(ctx)
+		if err != nil {
+			return silenceExitError(cmd, apiFailure(client, "listing models", err))
+		}
+
+		if modelsJSON {
+			if models == nil {
+				models = []ai.Model{}
+			}
+			return printJSON(models)
+		}
+		if len(models) == 0 {
+			fmt.Fprintln(os.Stderr, "The endpoint lists no models.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  MODEL\tOWNER\tCONTEXT")
+		for _, m := range models {
+			mark := " "
+			if ai.HasModel([]ai.Model{m}, cfg.Model) {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s %s\t%s\t%s\n", mark, m.ID, orDash(m.Owner), formatContext(m.ContextLength))
+		}
+		w.Flush()
+
+		if !ai.HasModel(models, cfg.Model) {
+			fmt.Fprintf(os.Stderr, "\033[33mThe configured model %q is not in this list — set one with 'aiterm config set model <name>'.\033[0m\n", cfg.Model)
+		}
+		return nil
+	},
+}
+
+func init() {
+	modelsCmd.Flags().BoolVar(&modelsJSON, "json", false, "Print the models as JSON")
+	rootCmd.AddCommand(modelsCmd)
+}
+
+// checkModel verifies that the endpoint of cfg offers model. When the list
+// cannot be fetched, it warns and lets the model through, since the
+// endpoint may just be unreachable right now.
+func checkModel(cfg *config.Config, model string) error {
+	probe := *cfg
+	probe.Model = model
+	// Without an endpoint and token there is nothing to ask yet.
+	if probe.Validate() != nil {
+		return nil
+	}
+
+	client := ai.NewClient(&probe)
+	ctx, cancel := apiContext(&probe)
+	defer cancel()
+
+	models, err := client.ListModels(ctx)
+	if errors.Is(err, context.Canceled) {
+		return apiFailure(client, "listing models", err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\033[33mCould not check the model against the endpoint: %v\033[0m\n", err)
+		return nil
+	}
+	if len(models) == 0 || ai.HasModel(models, model) {
+		return nil
+	}
+
+	msg := fmt.Sprintf("model %q is not offered by %s", model, probe.APIEndpoint)
+	if similar := ai.SimilarModels(models, model); len(similar) > 0 {
+		msg += " — did you mean " + strings.Join(similar, ", ") + "?"
+	}
+	return fmt.Errorf("%s\nRun 'aiterm models' to list them, or pass --force to set it anyway", msg)
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatContext renders a context length in tokens, such as "128k".
+func formatContext(tokens int) string {
+	switch {
+	case tokens == 0:
+		return "-"
+	case tokens%1024 == 0 && tokens >= 1024:
+		return fmt.Sprintf("%dk", tokens/1024)
+	case tokens%1000 == 0 && tokens >= 1000:
+		return fmt.Sprintf("%dk", tokens/1000)
+	}
+	return fmt.Sprint(tokens)
+}
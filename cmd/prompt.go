@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"aiterm/internal/ai"
+
+	"github.com/spf13/cobra"
+)
+
+var promptTarget string
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Inspect the prompt sent to the AI",
+}
+
+var promptShowCmd = &cobra.Command{
+	Use:   "show [description]",
+	Short: "Print the rendered messages of a generation request without sending it",
+	Long: `Renders the system and user messages exactly as 'aiterm <description>'
+would send them, including a prompt_template, the target OS and shell, tool
+hints and environment context. Nothing is sent to the API.
+
+Examples:
+  aiterm prompt show
+  aiterm prompt show -t linux/alpine --shell fish "install htop"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		description := strings.Join(args, " ")
+		if description == "" {
+			description = "<description>"
+		}
+
+		messages, err := ai.NewClient(cfg).PromptMessages(description, promptTarget)
+		if err != nil {
+			return err
+		}
+		for i, m := range messages {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("--- %s ---\n%s\n", m.Role, m.Content)
+		}
+		return nil
+	},
+}
+
+func init() {
+	promptShowCmd.Flags().StringVarP(&promptTarget, "type", "t", "", "Target OS type: win, linux, linux/<distro>, mac (auto-detected if omitted)")
+	promptCmd.AddCommand(promptShowCmd)
+	rootCmd.AddCommand(promptCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var refineCmd = &cobra.Command{
+	Use:   "refine <follow-up>",
+	Short: "Refine the previous command with a follow-up request",
+	Long: `Sends the previous exchange (prompt, command and target) together with a
+follow-up request, so corrections build on the last answer instead of
+starting over. Same as 'aiterm --continue <follow-up>'.
+
+Examples:
+  aiterm "list big files"
+  aiterm refine "only in /var"
+  aiterm refine "sort descending"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		continueSession = true
+		return silenceExitError(cmd, runGenerate(strings.Join(args, " "), targetType))
+	},
+}
+
+func init() {
+	refineCmd.Flags().StringVarP(&targetType, "type", "t", "", "Target OS type: win, linux, linux/<distro>, mac (defaults to the previous command's target)")
+	refineCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Run the refined command without asking for confirmation")
+	refineCmd.Flags().BoolVarP(&printOnly, "print", "p", false, "Only print the refined command, never run it")
+	refineCmd.MarkFlagsMutuallyExclusive("yes", "print")
+	rootCmd.AddCommand(refineCmd)
+}
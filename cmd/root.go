@@ -2,83 +2,498 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"aiterm/internal/ai"
+	"aiterm/internal/cassette"
 	"aiterm/internal/config"
+	"aiterm/internal/ledger"
+	"aiterm/internal/session"
+	"aiterm/internal/shell"
+	"aiterm/internal/tools"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 // Version is set at build time via ldflags.
 var Version = "dev"
 
 var (
-	debug      bool
-	targetType string
+	debug           bool
+	verbose         bool
+	targetType      string
+	assumeYes       bool
+	printOnly       bool
+	alternatives    int
+	continueSession bool
+	showContext     bool
+	shellFlag       string
+	noCache         bool
+	offlineOnly     bool
+	cassettePath    string
+	cassetteMode    string
+	timeoutFlag     string
 )
 
+// exitInterrupted is the exit status after Ctrl-C, as in shells
+// (128 + SIGINT).
+const exitInterrupted = 130
+
 var rootCmd = &cobra.Command{
 	Use:   "aiterm [prompt]",
 	Short: "AI-powered terminal command generator",
 	Long: `aiterm generates shell commands from natural language descriptions.
-Simply describe what you want and aiterm prints the command you can run.
+Simply describe what you want, review the suggested command and run it.
 
 Examples:
   aiterm "list all files larger than 100MB"
   aiterm "find all PDFs modified in the last 7 days" -t linux
-  aiterm "show disk usage sorted by size" -t mac`,
+  aiterm "install htop" -t linux/alpine
+  aiterm "show disk usage sorted by size" -t mac
+  aiterm "count lines in all Go files" --yes
+  aiterm --continue "only in /var"`,
 	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if showContext {
+			return runShowContext()
+		}
 		if len(args) == 0 {
 			return cmd.Help()
 		}
 
 		prompt := strings.Join(args, " ")
-		return runGenerate(prompt, targetType)
+		return silenceExitError(cmd, runGenerate(prompt, targetType))
 	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging to ~/.aiterm/debug.log")
-	rootCmd.Flags().StringVarP(&targetType, "type", "t", "", "Target OS type: win, linux, mac (auto-detected if omitted)")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Show token usage and cost on stderr")
+	rootCmd.PersistentFlags().BoolVar(&offlineOnly, "offline", false, "Suggest a command from the built-in offline library without contacting the API")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Ask the model even if the answer is cached")
+	rootCmd.PersistentFlags().StringVar(&timeoutFlag, "timeout", "", "Limit for each API call, such as 45s or 2m (overrides the timeout config key)")
+	rootCmd.PersistentFlags().StringVar(&shellFlag, "shell", "", "Shell to generate for: "+strings.Join(shell.IDs(), ", ")+" (overrides the shell config key)")
+
+	// Cassettes are for capturing and replaying sessions in tests.
+	rootCmd.PersistentFlags().StringVar(&cassettePath, "cassette", "", "Record API exchanges to or replay them from this file")
+	rootCmd.PersistentFlags().StringVar(&cassetteMode, "cassette-mode", "", "Cassette mode: record, replay or lenient")
+	rootCmd.PersistentFlags().MarkHidden("cassette")
+	rootCmd.PersistentFlags().MarkHidden("cassette-mode")
+	cobra.OnInitialize(applyCassetteFlags)
+
+	rootCmd.Flags().StringVarP(&targetType, "type", "t", "", "Target OS type: win, linux, linux/<distro>, mac (auto-detected if omitted)")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Run the generated command without asking for confirmation")
+	rootCmd.Flags().BoolVarP(&printOnly, "print", "p", false, "Only print the generated command, never run it")
+	rootCmd.Flags().IntVarP(&alternatives, "alternatives", "n", 1, "Generate up to N distinct commands and pick one")
+	rootCmd.Flags().BoolVarP(&continueSession, "continue", "c", false, "Treat the prompt as a follow-up to the previous command")
+	rootCmd.Flags().BoolVar(&showContext, "show-context", false, "Print the environment context that would be sent, then exit")
+	rootCmd.MarkFlagsMutuallyExclusive("yes", "print")
+	rootCmd.MarkFlagsMutuallyExclusive("continue", "alternatives")
 }
 
 // Execute runs the root command.
 func Execute() {
+	silenceUsageOnRun(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
 
-// runGenerate sends the prompt to the AI and prints the suggested command.
-func runGenerate(prompt, target string) error {
+// silenceUsageOnRun makes cmd and its subcommands print their usage only
+// for flag and argument errors. Once RunE is reached, a failure is about
+// the API or the system rather than how aiterm was invoked.
+func silenceUsageOnRun(cmd *cobra.Command) {
+	for _, sub := range cmd.Commands() {
+		silenceUsageOnRun(sub)
+	}
+	run := cmd.RunE
+	if run == nil {
+		return
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return run(cmd, args)
+	}
+}
+
+// applyCassetteFlags passes the hidden cassette flags on to every API
+// client through the environment, where they override the variables.
+func applyCassetteFlags() {
+	if cassettePath != "" {
+		os.Setenv(cassette.EnvPath, cassettePath)
+	}
+	if cassetteMode != "" {
+		os.Setenv(cassette.EnvMode, cassetteMode)
+	}
+}
+
+// loadConfig loads the configuration and applies the global flags that
+// override it for this invocation. The result must not be saved.
+func loadConfig() (*config.Config, error) {
 	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if err := cfg.Validate(); err != nil {
+	if shellFlag != "" {
+		if _, ok := shell.Lookup(shellFlag); !ok {
+			return nil, fmt.Errorf("unknown shell %q — must be one of: %s", shellFlag, strings.Join(shell.IDs(), ", "))
+		}
+		cfg.Shell = shellFlag
+	}
+	if timeoutFlag != "" {
+		if d, err := time.ParseDuration(timeoutFlag); err != nil || d <= 0 {
+			return nil, fmt.Errorf("--timeout must be a positive duration such as 45s or 2m")
+		}
+		cfg.Timeout = timeoutFlag
+	}
+	// A cassette must see every request, so cached answers are bypassed.
+	if noCache || os.Getenv(cassette.EnvPath) != "" {
+		cfg.Cache = false
+	}
+
+	if err := cfg.CheckPromptTemplate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// apiContext returns the context for an API call: limited to the
+// configured timeout and cancelled by Ctrl-C or SIGTERM, which don't
+// terminate aiterm until cancel is called.
+func apiContext(cfg *config.Config) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(ctx, cfg.TimeoutDuration())
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// apiFailure turns the error of an API call into the error of the command,
+// with what naming the call. An interrupted call is reported on stderr,
+// along with any partial reply, and exits with exitInterrupted.
+func apiFailure(client *ai.Client, what string, err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		if partial := strings.TrimSpace(client.Partial()); partial != "" {
+			fmt.Fprintf(os.Stderr, "\033[33m[interrupted — incomplete reply, not run: %s]\033[0m\n", partial)
+		} else {
+			fmt.Fprintln(os.Stderr, "\033[33m[interrupted — nothing was run]\033[0m")
+		}
+		return &exitError{code: exitInterrupted}
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%s failed: %w — raise the limit with --timeout or 'aiterm config set timeout'", what, err)
+	}
+	return fmt.Errorf("%s failed: %w", what, err)
+}
+
+// silenceExitError stops cobra from printing err when it only carries the
+// exit status of an executed command, which already reported its failure.
+func silenceExitError(cmd *cobra.Command, err error) error {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// runGenerate sends the prompt to the AI, shows the suggested command and,
+// once confirmed, runs it in the target shell. With --continue the prompt
+// refines the previous command instead.
+func runGenerate(prompt, target string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// The offline library needs no credentials.
+	if err := cfg.Validate(); err != nil && !offlineOnly {
 		fmt.Fprintln(os.Stderr, "AI not configured. Run 'aiterm setup' first.")
 		return err
 	}
 
-	osName, shellType := ai.ResolveTargetOS(target)
-	fmt.Fprintf(os.Stderr, "\033[90m[%s / %s] Generating...\033[0m\n", osName, shellType)
+	var prev *ai.Exchange
+	if continueSession {
+		if offlineOnly {
+			return fmt.Errorf("refining a command needs the API — drop --offline")
+		}
+		if prev, err = session.Load(); err != nil {
+			return err
+		}
+		// Follow-ups stay on the previous target unless -t says otherwise.
+		if target == "" {
+			target = prev.Target
+		}
+		prev.Target = target
+		fmt.Fprintf(os.Stderr, "\033[90mRefining: %s\033[0m\n", prev.Command)
+	}
+
+	osName, shellType := ai.ResolveTarget(target, cfg.Shell)
+	fmt.Fprintf(os.Stderr, "\033[90m[%s / %s] Generating...\033[0m\n", targetLabel(target, osName), shellType)
 
 	client := ai.NewClient(cfg)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	ctx, cancel := apiContext(cfg)
+	commands, err := generateCommands(ctx, client, prev, prompt, target)
+	// Ctrl-C at the prompts below quits as usual.
+	cancel()
+	if err != nil {
+		return apiFailure(client, "generation", err)
+	}
+	reportEndpoint(cfg, client)
+	reportCached(client)
+	reportOffline(client)
+	reportDiscarded(client)
+	reportUsage(cfg, client)
+	warnMissingTools(commands, target, shellType)
 
-	command, err := client.GenerateCommand(ctx, prompt, target)
+	saveSession(client, commands[0])
+
+	if printOnly {
+		// Print the commands to stdout so the user can copy/pipe them
+		printCommands(commands, "\n")
+		return nil
+	}
+
+	// Commands generated for another OS or distribution can only be printed.
+	if !ai.IsLocalTarget(target) {
+		fmt.Fprintf(os.Stderr, "\033[90mTarget is %s; printing the command instead of running it.\033[0m\n", targetLabel(target, osName))
+		printCommands(commands, "\n")
+		return nil
+	}
+
+	// Offline answers are only matched by keywords, so they are never run
+	// without confirmation.
+	yes := assumeYes
+	if _, ok := client.Offline(); ok && yes {
+		fmt.Fprintln(os.Stderr, "\033[33m[--yes is ignored for offline suggestions]\033[0m")
+		yes = false
+	}
+
+	// Without a terminal there is nobody to confirm or pick, so only print.
+	if !term.IsTerminal(int(os.Stdin.Fd())) && (!yes || len(commands) > 1) {
+		printCommands(commands, "\n")
+		return nil
+	}
+
+	command := commands[0]
+	if len(commands) > 1 {
+		if command, err = pickCommand(commands); err != nil || command == "" {
+			return err
+		}
+		saveSession(client, command)
+	}
+
+	return confirmAndRun(context.Background(), command, execShell(cfg, shellType), yes)
+}
+
+// runShowContext prints the environment context exactly as it would be
+// appended to the system prompt.
+func runShowContext() error {
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("generation failed: %w", err)
+		return err
 	}
 
-	// Print the command to stdout so the user can copy/pipe it
-	fmt.Println(command)
+	env := ai.NewClient(cfg).Environment()
+	if env == "" {
+		fmt.Fprintln(os.Stderr, "No environment context is sent. Enable sources with 'aiterm config set context.<cwd|listing|git|package_manager> true'.")
+		return nil
+	}
+	fmt.Println(env)
 	return nil
 }
+
+// generateCommands asks for --alternatives candidates, or streams a single
+// command live so slow backends don't look frozen. With a previous exchange
+// the prompt is sent as a follow-up to it; with --offline only the offline
+// library is consulted.
+func generateCommands(ctx context.Context, client *ai.Client, prev *ai.Exchange, prompt, target string) ([]string, error) {
+	if offlineOnly {
+		return client.SuggestOffline(prompt, target, alternatives)
+	}
+	if alternatives > 1 {
+		return client.GenerateAlternatives(ctx, prompt, target, alternatives)
+	}
+
+	onToken, done := liveTokens()
+	var command string
+	var err error
+	if prev != nil {
+		command, err = client.RefineCommand(ctx, prev, prompt, onToken)
+	} else {
+		command, err = client.GenerateCommandStream(ctx, prompt, target, onToken)
+	}
+	done()
+	if err != nil {
+		return nil, err
+	}
+	return []string{command}, nil
+}
+
+// liveTokens returns a callback that renders streamed tokens in gray on
+// stderr, and a function that ends the line once streaming is over.
+func liveTokens() (onToken func(string), done func()) {
+	streamed := false
+	onToken = func(token string) {
+		if !streamed {
+			fmt.Fprint(os.Stderr, "\033[90m")
+			streamed = true
+		}
+		fmt.Fprint(os.Stderr, token)
+	}
+	done = func() {
+		if streamed {
+			fmt.Fprintln(os.Stderr, "\033[0m")
+		}
+	}
+	return onToken, done
+}
+
+// saveSession stores the client's last exchange, with command as its
+// answer, so that 'aiterm refine' can build on it. Failing to save only
+// warns: the command itself was generated fine.
+func saveSession(client *ai.Client, command string) {
+	ex, ok := client.LastExchange()
+	if !ok {
+		return
+	}
+	ex.Choose(command)
+	if err := session.Save(ex); err != nil {
+		fmt.Fprintf(os.Stderr, "\033[90mwarning: %v\033[0m\n", err)
+	}
+}
+
+// printCommands writes each command to stdout followed by sep.
+func printCommands(commands []string, sep string) {
+	for _, c := range commands {
+		fmt.Print(c + sep)
+	}
+}
+
+// reportEndpoint tells the user on stderr which endpoint answered when a
+// failover chain is configured.
+func reportEndpoint(cfg *config.Config, client *ai.Client) {
+	if len(cfg.Endpoints) == 0 {
+		return
+	}
+	if ep, ok := client.AnsweredBy(); ok {
+		fmt.Fprintf(os.Stderr, "\033[90m[answered by %s]\033[0m\n", ep.Name())
+	}
+}
+
+// reportCached tells the user on stderr when the answer came from the
+// response cache.
+func reportCached(client *ai.Client) {
+	if client.Cached() {
+		fmt.Fprintln(os.Stderr, "\033[90m[cached answer; --no-cache asks the model again]\033[0m")
+	}
+}
+
+// reportOffline labels answers from the offline library on stderr, with
+// the reason when the API could not be reached.
+func reportOffline(client *ai.Client) {
+	answer, ok := client.Offline()
+	if !ok {
+		return
+	}
+	if answer.Cause != nil {
+		fmt.Fprintf(os.Stderr, "\033[33m[API unreachable (%v)]\033[0m\n", answer.Cause)
+	}
+	fmt.Fprintf(os.Stderr, "\033[33m[offline suggestion: %s — review before running]\033[0m\n", answer.Task)
+}
+
+// reportDiscarded tells the user on stderr when extra text around the
+// command had to be dropped from the reply; --verbose shows the text.
+func reportDiscarded(client *ai.Client) {
+	discarded := client.Discarded()
+	if len(discarded) == 0 {
+		return
+	}
+	noun := "lines"
+	if len(discarded) == 1 {
+		noun = "line"
+	}
+	fmt.Fprintf(os.Stderr, "\033[90m[discarded %d %s of extra text from the reply]\033[0m\n", len(discarded), noun)
+	if verbose {
+		for _, line := range discarded {
+			fmt.Fprintf(os.Stderr, "\033[90m  %s\033[0m\n", line)
+		}
+	}
+}
+
+// targetLabel describes the target OS for status lines, including the
+// Linux distribution when known, as in "Linux (fedora 39)".
+func targetLabel(target, osName string) string {
+	info, ok := ai.ResolveDistro(target)
+	if !ok {
+		return osName
+	}
+	name := info.ID
+	if info.VersionID != "" {
+		name += " " + info.VersionID
+	}
+	return fmt.Sprintf("%s (%s)", osName, name)
+}
+
+// warnMissingTools warns on stderr about commands whose leading program is
+// not installed. Only POSIX-style commands for this machine are checked;
+// PowerShell cmdlets and Nushell built-ins never live on PATH.
+func warnMissingTools(commands []string, target, shellType string) {
+	if !ai.IsLocalTarget(target) {
+		return
+	}
+	if spec, ok := shell.Lookup(shellType); !ok || !spec.POSIXLike() {
+		return
+	}
+
+	warned := map[string]bool{}
+	for _, command := range commands {
+		if binary, ok := tools.Missing(command, shellType); ok && !warned[binary] {
+			warned[binary] = true
+			fmt.Fprintf(os.Stderr, "\033[33mwarning: %s is not installed (not found on PATH)\033[0m\n", binary)
+		}
+	}
+}
+
+// reportUsage records the client's token usage in the ledger and, with
+// --verbose, shows it on stderr.
+func reportUsage(cfg *config.Config, client *ai.Client) {
+	u, ok := client.Usage()
+	if !ok {
+		return
+	}
+
+	entry := ledger.Entry{
+		Time:             time.Now(),
+		Model:            u.Model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		Cost:             u.Cost,
+	}
+	err := ledger.Append(entry)
+	if !verbose {
+		return
+	}
+
+	line := fmt.Sprintf("[%s: %d prompt + %d completion tokens", u.Model, u.PromptTokens, u.CompletionTokens)
+	if cost, ok := ledger.EntryCost(entry, cfg); ok {
+		line += fmt.Sprintf(", $%.6f", cost)
+	}
+	fmt.Fprintf(os.Stderr, "\033[90m%s]\033[0m\n", line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\033[90mwarning: %v\033[0m\n", err)
+	}
+}
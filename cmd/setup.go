@@ -3,10 +3,11 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
-	"time"
 
 	"aiterm/internal/ai"
 	"aiterm/internal/config"
@@ -20,7 +21,7 @@ var setupCmd = &cobra.Command{
 	Short: "Interactive setup wizard",
 	Long:  `Guides you through configuring aiterm with your API credentials and preferences.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runSetup()
+		return silenceExitError(cmd, runSetup())
 	},
 }
 
@@ -43,6 +44,19 @@ func runSetup() error {
 		cfg = config.DefaultConfig()
 	}
 
+	// Provider
+	fmt.Printf("Provider (%s) [%s]: ", strings.Join(config.Providers, ", "), cfg.Provider)
+	provider, _ := reader.ReadString('\n')
+	provider = strings.TrimSpace(provider)
+	if provider != "" {
+		previous := cfg.Provider
+		cfg.Provider = strings.ToLower(provider)
+		if err := cfg.Validate(); err != nil && strings.Contains(err.Error(), "unknown provider") {
+			fmt.Printf("Unknown provider %q, keeping %s\n", provider, previous)
+			cfg.Provider = previous
+		}
+	}
+
 	// API Endpoint
 	fmt.Printf("API Endpoint [%s]: ", cfg.APIEndpoint)
 	endpoint, _ := reader.ReadString('\n')
@@ -71,21 +85,23 @@ func runSetup() error {
 	}
 
 	// Model
-	fmt.Printf("Model [%s]: ", cfg.Model)
-	model, _ := reader.ReadString('\n')
-	model = strings.TrimSpace(model)
-	if model != "" {
-		cfg.Model = model
+	if err := chooseModel(reader, cfg); err != nil {
+		return err
 	}
 
 	// Test connection
-	if cfg.APIToken != "" {
+	if cfg.Validate() == nil {
 		fmt.Print("\nTesting API connection... ")
 		client := ai.NewClient(cfg)
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-		defer cancel()
+		ctx, cancel := apiContext(cfg)
+		err := client.TestConnection(ctx)
+		cancel()
 
-		if err := client.TestConnection(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Println()
+			return apiFailure(client, "connection test", err)
+		}
+		if err != nil {
 			fmt.Printf("✗ Failed: %v\n", err)
 			fmt.Println("Configuration will be saved anyway. You can update it later with 'aiterm config set'.")
 		} else {
@@ -105,3 +121,78 @@ func runSetup() error {
 
 	return nil
 }
+
+// setupMenuSize is the number of models shown in the setup menu; longer
+// lists are cut, and the other models can still be typed by name.
+const setupMenuSize = 30
+
+// chooseModel asks for the model, offering the models the endpoint lists
+// as a numbered menu. Without a list it falls back to a plain prompt.
+func chooseModel(reader *bufio.Reader, cfg *config.Config) error {
+	var models []ai.Model
+
+	// The listing needs the endpoint and token, but no model yet.
+	probe := *cfg
+	if probe.Model == "" {
+		probe.Model = config.DefaultConfig().Model
+	}
+	if probe.Validate() == nil {
+		fmt.Print("\nFetching available models... ")
+		client := ai.NewClient(&probe)
+		ctx, cancel := apiContext(&probe)
+		list, err := client.ListModels(ctx)
+		cancel()
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			fmt.Println()
+			return apiFailure(client, "listing models", err)
+		case err != nil:
+			fmt.Printf("✗ %v\n", err)
+		default:
+			fmt.Printf("%d found\n", len(list))
+			models = list
+		}
+	}
+
+	for i, m := range models {
+		if i == setupMenuSize {
+			fmt.Printf("  ... and %d more — type a name, or see them all with 'aiterm models'\n", len(models)-setupMenuSize)
+			break
+		}
+		mark := " "
+		if ai.HasModel([]ai.Model{m}, cfg.Model) {
+			mark = "*"
+		}
+		fmt.Printf(" %s%3d) %s\n", mark, i+1, m.ID)
+	}
+
+	for {
+		if len(models) > 0 {
+			fmt.Printf("Model (number or name) [%s]: ", cfg.Model)
+		} else {
+			fmt.Printf("Model [%s]: ", cfg.Model)
+		}
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			return nil
+		}
+
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= min(len(models), setupMenuSize) {
+			cfg.Model = models[n-1].ID
+			return nil
+		}
+		// Without a list, or at the end of input, take the name as given.
+		if len(models) == 0 || ai.HasModel(models, answer) || err != nil {
+			cfg.Model = answer
+			return nil
+		}
+
+		fmt.Printf("The endpoint doesn't offer %q.", answer)
+		if similar := ai.SimilarModels(models, answer); len(similar) > 0 {
+			fmt.Printf(" Did you mean %s?", strings.Join(similar, ", "))
+		}
+		fmt.Println()
+	}
+}
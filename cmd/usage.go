@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"aiterm/internal/config"
+	"aiterm/internal/ledger"
+
+	"github.com/spf13/cobra"
+)
+
+var usageSince string
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and estimated cost per model",
+	Long: `Totals the tokens and cost recorded in ~/.aiterm/usage.jsonl per model.
+
+Costs reported by the endpoint (LiteLLM's x-litellm-response-cost header)
+are used as is; everything else is estimated from the price table, set in
+USD per million input and output tokens:
+
+  aiterm config set price.gpt-4o-mini 0.15,0.60
+
+Examples:
+  aiterm usage
+  aiterm usage --since 7d
+  aiterm usage --since 2024-01-01`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		since, err := ledger.ParseSince(usageSince, time.Now())
+		if err != nil {
+			return err
+		}
+
+		entries, err := ledger.Load(since)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No usage recorded since %s.\n", since.Format("2006-01-02"))
+			return nil
+		}
+
+		totals := ledger.Summarize(entries, cfg)
+		var sum ledger.Total
+		unpriced := false
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODEL\tREQUESTS\tPROMPT\tCOMPLETION\tCOST")
+		for _, t := range totals {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", t.Model, t.Requests, t.PromptTokens, t.CompletionTokens, formatCost(t))
+			sum.Requests += t.Requests
+			sum.PromptTokens += t.PromptTokens
+			sum.CompletionTokens += t.CompletionTokens
+			sum.Cost += t.Cost
+			unpriced = unpriced || t.Unpriced > 0
+		}
+		fmt.Fprintf(w, "TOTAL\t%d\t%d\t%d\t$%.4f\n", sum.Requests, sum.PromptTokens, sum.CompletionTokens, sum.Cost)
+		w.Flush()
+
+		if unpriced {
+			fmt.Fprintln(os.Stderr, "\n* some requests have no price — set one with 'aiterm config set price.<model> <input>,<output>'")
+		}
+		return nil
+	},
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageSince, "since", "30d", "Only count usage since this period (30d, 2w, 12h) or date (2024-01-31)")
+	rootCmd.AddCommand(usageCmd)
+}
+
+// formatCost renders a model's cost, marking totals that leave out
+// requests without a known price.
+func formatCost(t ledger.Total) string {
+	if t.Unpriced == t.Requests {
+		return "-"
+	}
+	cost := fmt.Sprintf("$%.4f", t.Cost)
+	if t.Unpriced > 0 {
+		cost += "*"
+	}
+	return cost
+}
@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/spf13/cobra v1.8.1
 	golang.org/x/term v0.27.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GenerateAlternatives asks for up to n distinct commands for the same
+// description. Providers that support several choices per request are asked
+// for all of them at once; any shortfall (including duplicates) is made up
+// by sampling again while telling the model which commands it already
+// suggested. The result has at least one and at most n commands; when no
+// reply holds a command, GenerateAlternatives fails.
+func (c *Client) GenerateAlternatives(ctx context.Context, description, targetOS string, n int) ([]string, error) {
+	if err := c.cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	req, err := c.commandRequest(description, targetOS, false)
+	if err != nil {
+		return nil, err
+	}
+	req.N = n
+
+	key := c.cacheKey(req, description, targetOS, n)
+	if commands, ok := c.fromCache(key); ok {
+		c.remember(description, targetOS, req.Messages, commands[0])
+		return commands, nil
+	}
+
+	choices, err := c.complete(ctx, req)
+	if err != nil {
+		return c.fallBackOffline(ctx, err, description, targetOS, n)
+	}
+
+	var commands []string
+	commands = appendUnique(commands, c.sanitizeAll(choices)...)
+
+	// Bound the extra round trips so a model that keeps repeating itself
+	// cannot loop forever.
+	cutShort := false
+	for attempt := 0; len(commands) < n && attempt < n; attempt++ {
+		req, err := c.commandRequest(description, targetOS, false)
+		if err != nil {
+			cutShort = true
+			break
+		}
+		req.Messages[len(req.Messages)-1].Content += avoidHint(commands)
+
+		choices, err := c.complete(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, &ctxError{err: ctx.Err()}
+			}
+			// Keep what we already have rather than failing the whole call.
+			cutShort = true
+			break
+		}
+		commands = appendUnique(commands, c.sanitizeAll(choices)...)
+	}
+
+	if len(commands) == 0 {
+		return nil, errNoCommand
+	}
+	if len(commands) > n {
+		commands = commands[:n]
+	}
+	// A list cut short by an error is not what the model would answer, so
+	// a later run should ask again instead of reusing it.
+	if !cutShort || len(commands) == n {
+		c.toCache(key, commands)
+	}
+	c.remember(description, targetOS, req.Messages, commands[0])
+	return commands, nil
+}
+
+// avoidHint asks the model for a variant other than the given commands.
+func avoidHint(commands []string) string {
+	var b strings.Builder
+	b.WriteString("\nSuggest a different variant than these already suggested commands")
+	b.WriteString(" (for example other tools or flags):")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\n- %s", cmd)
+	}
+	return b.String()
+}
+
+// sanitizeAll sanitizes every reply.
+func (c *Client) sanitizeAll(replies []string) []string {
+	commands := make([]string, len(replies))
+	for i, r := range replies {
+		commands[i] = c.sanitize(r)
+	}
+	return commands
+}
+
+// appendUnique appends each sanitized command to commands unless it is
+// empty or an equivalent command is already present.
+func appendUnique(commands []string, candidates ...string) []string {
+	for _, cmd := range candidates {
+		if cmd == "" {
+			continue
+		}
+
+		duplicate := false
+		for _, existing := range commands {
+			if normalizeCommand(existing) == normalizeCommand(cmd) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+// normalizeCommand reduces a command to a canonical form for comparison:
+// whitespace runs collapse to one space and trailing semicolons are dropped.
+func normalizeCommand(cmd string) string {
+	cmd = strings.Join(strings.Fields(cmd), " ")
+	return strings.TrimRight(cmd, "; ")
+}
@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestGenerateAlternatives_UsesNAndDeduplicates(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		var contents []string
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			if req.N != 3 {
+				t.Errorf("expected n=3 in first request, got %d", req.N)
+			}
+			contents = []string{"find . -name '*.go'", "find  . -name '*.go';", "fd -e go"}
+		default:
+			last := req.Messages[len(req.Messages)-1].Content
+			if !strings.Contains(last, "fd -e go") {
+				t.Errorf("expected follow-up request to list earlier suggestions, got %q", last)
+			}
+			contents = []string{"```\nls **/*.go\n```"}
+		}
+
+		var choices []map[string]interface{}
+		for _, c := range contents {
+			choices = append(choices, map[string]interface{}{"message": map[string]string{"content": c}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"choices": choices})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	commands, err := client.GenerateAlternatives(testContext(t), "find go files", "", 3)
+	if err != nil {
+		t.Fatalf("GenerateAlternatives failed: %v", err)
+	}
+
+	expected := []string{"find . -name '*.go'", "fd -e go", "ls **/*.go"}
+	if strings.Join(commands, "|") != strings.Join(expected, "|") {
+		t.Errorf("got %q, want %q", commands, expected)
+	}
+}
+
+func TestGenerateAlternatives_RepeatedSampling(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"message": {"content": "echo %d"}, "done": true}`, n)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOllama)
+
+	commands, err := client.GenerateAlternatives(testContext(t), "say something", "", 2)
+	if err != nil {
+		t.Fatalf("GenerateAlternatives failed: %v", err)
+	}
+	if len(commands) != 2 || commands[0] != "echo 1" || commands[1] != "echo 2" {
+		t.Errorf("unexpected commands: %q", commands)
+	}
+}
+
+func TestGenerateAlternatives_InterruptedWhileResampling(t *testing.T) {
+	ctx, cancel := context.WithCancel(testContext(t))
+	defer cancel()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			// Ctrl-C while the second sample is requested.
+			cancel()
+			return
+		}
+		fmt.Fprint(w, `{"message": {"content": "echo 1"}, "done": true}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOllama)
+
+	commands, err := client.GenerateAlternatives(ctx, "say something", "", 3)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %q, %v", commands, err)
+	}
+}
+
+func TestGenerateAlternatives_ShortListNotCached(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every second request fails, so each run gets one command only.
+		if atomic.AddInt32(&calls, 1)%2 == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"message": {"content": "echo 1"}, "done": true}`)
+	}))
+	defer server.Close()
+
+	for run := 1; run <= 2; run++ {
+		client := newTestClient(server.URL, config.ProviderOllama)
+		client.cfg.Cache = true
+		client.cfg.CacheMaxEntries = 10
+
+		commands, err := client.GenerateAlternatives(testContext(t), "say something", "", 3)
+		if err != nil || len(commands) != 1 {
+			t.Fatalf("run %d: got %q, %v", run, commands, err)
+		}
+		if client.Cached() {
+			t.Errorf("run %d: expected a list cut short by an error not to be cached", run)
+		}
+	}
+}
+
+func TestNormalizeCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ls -la", "ls -la"},
+		{"  ls   -la  ", "ls -la"},
+		{"ls -la;", "ls -la"},
+		{"ls\t-la ;", "ls -la"},
+	}
+
+	for _, tt := range tests {
+		if result := normalizeCommand(tt.input); result != tt.expected {
+			t.Errorf("normalizeCommand(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestGenerate_NoCommandLeft(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "<think>Which tool fits best?</think>"}}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	if commands, err := client.GenerateAlternatives(testContext(t), "find go files", "", 2); !errors.Is(err, errNoCommand) {
+		t.Errorf("GenerateAlternatives = %q, %v; want errNoCommand", commands, err)
+	}
+	if command, err := client.GenerateCommand(testContext(t), "find go files", ""); !errors.Is(err, errNoCommand) {
+		t.Errorf("GenerateCommand = %q, %v; want errNoCommand", command, err)
+	}
+	if command, err := client.GenerateCommandStream(testContext(t), "find go files", "", nil); !errors.Is(err, errNoCommand) {
+		t.Errorf("GenerateCommandStream = %q, %v; want errNoCommand", command, err)
+	}
+}
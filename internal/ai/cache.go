@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"strings"
+
+	"aiterm/internal/cache"
+)
+
+// Cached reports whether the most recent generation was answered from the
+// response cache instead of the model.
+func (c *Client) Cached() bool {
+	return c.cached
+}
+
+// cacheKey identifies a command request for the response cache. The
+// prompt version covers everything sent besides the description: system
+// prompt, distribution, environment context and few-shot examples.
+func (c *Client) cacheKey(req completionRequest, description, targetOS string, n int) cache.Key {
+	osName, shellType := c.resolveTarget(targetOS)
+
+	var models []string
+	for _, ep := range c.cfg.EndpointChain() {
+		models = append(models, ep.Name())
+	}
+
+	var sent strings.Builder
+	for _, m := range req.Messages[:len(req.Messages)-1] {
+		sent.WriteString(m.Role + "\x00" + m.Content + "\x00")
+	}
+
+	return cache.Key{
+		Prompt:        description,
+		OS:            osName,
+		Shell:         shellType,
+		Model:         strings.Join(models, "\n"),
+		PromptVersion: cache.Version(sent.String()),
+		N:             n,
+	}
+}
+
+// fromCache returns the cached commands for key, if caching is enabled.
+func (c *Client) fromCache(key cache.Key) ([]string, bool) {
+	if !c.cfg.Cache {
+		return nil, false
+	}
+	store, err := cache.Open(c.cfg.CacheTTLDuration(), c.cfg.CacheMaxEntries)
+	if err != nil {
+		return nil, false
+	}
+	e, ok := store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	c.cached = true
+	return e.Commands, true
+}
+
+// toCache stores commands under key, if caching is enabled. Failures are
+// ignored: the cache is only an optimization.
+func (c *Client) toCache(key cache.Key, commands []string) {
+	if !c.cfg.Cache || len(commands) == 0 || commands[0] == "" {
+		return
+	}
+	store, err := cache.Open(c.cfg.CacheTTLDuration(), c.cfg.CacheMaxEntries)
+	if err != nil {
+		return
+	}
+	e := cache.Entry{Prompt: cache.NormalizePrompt(key.Prompt), Commands: commands}
+	if ep, ok := c.AnsweredBy(); ok {
+		e.Model = ep.Model
+	}
+	store.Put(key, e)
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestGenerateCommand_Cache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": fmt.Sprintf("echo %d", calls)}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	newClient := func(model, shellName string) *Client {
+		client := newTestClient(server.URL, config.ProviderOpenAI)
+		client.cfg.Model = model
+		client.cfg.Shell = shellName
+		client.cfg.Cache = true
+		client.cfg.CacheMaxEntries = 10
+		return client
+	}
+
+	first := newClient("m", "bash")
+	if cmd, err := first.GenerateCommand(testContext(t), "say hi", "linux"); err != nil || cmd != "echo 1" || first.Cached() {
+		t.Fatalf("first request: %q, %v, cached %v", cmd, err, first.Cached())
+	}
+
+	// The same request, spelled slightly differently, is served from disk.
+	second := newClient("m", "bash")
+	cmd, err := second.GenerateCommandStream(testContext(t), "  say   hi ", "linux", func(string) {
+		t.Error("expected no tokens for a cached answer")
+	})
+	if err != nil || cmd != "echo 1" || !second.Cached() || calls != 1 {
+		t.Fatalf("second request: %q, %v, cached %v, %d calls", cmd, err, second.Cached(), calls)
+	}
+	if ex, ok := second.LastExchange(); !ok || ex.Command != "echo 1" {
+		t.Error("expected a cached answer to be remembered for refine")
+	}
+
+	// Another model, shell or number of alternatives asks the model again.
+	if cmd, _ := newClient("other", "bash").GenerateCommand(testContext(t), "say hi", "linux"); cmd != "echo 2" {
+		t.Errorf("expected a miss for another model, got %q", cmd)
+	}
+	if cmd, _ := newClient("m", "zsh").GenerateCommand(testContext(t), "say hi", "linux"); cmd != "echo 3" {
+		t.Errorf("expected a miss for another shell, got %q", cmd)
+	}
+	if cmds, _ := newClient("m", "bash").GenerateAlternatives(testContext(t), "say hi", "linux", 2); len(cmds) == 0 || cmds[0] != "echo 4" {
+		t.Errorf("expected a miss for alternatives, got %q", cmds)
+	}
+
+	// With the cache disabled, nothing is read.
+	disabled := newClient("m", "bash")
+	disabled.cfg.Cache = false
+	if cmd, _ := disabled.GenerateCommand(testContext(t), "say hi", "linux"); disabled.Cached() || cmd == "echo 1" {
+		t.Errorf("expected the cache to be bypassed, got %q", cmd)
+	}
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"aiterm/internal/cassette"
+	"aiterm/internal/config"
+)
+
+// replayClient returns a client whose requests are answered from the
+// cassette in testdata/cassettes, matched leniently since the prompts
+// depend on the machine running the tests.
+func replayClient(t *testing.T, name, endpoint, provider string) *Client {
+	t.Helper()
+	rt, err := cassette.New(filepath.Join("testdata", "cassettes", name), cassette.ReplayLenient, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(endpoint, provider)
+	client.cfg.Shell = "bash"
+	client.SetTransport(rt)
+	return client
+}
+
+func TestReplay_OpenAIStream(t *testing.T) {
+	client := replayClient(t, "openai_stream.json", "https://api.openai.com/v1/chat/completions", config.ProviderOpenAI)
+
+	var streamed string
+	cmd, err := client.GenerateCommandStream(testContext(t), "delete logs older than a week", "linux", func(token string) {
+		streamed += token
+	})
+	if err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+	if cmd != "find . -type f -name '*.log' -mtime +7" {
+		t.Errorf("unexpected command %q", cmd)
+	}
+	if streamed != "```bash\nfind . -type f -name '*.log' -mtime +7\n```" {
+		t.Errorf("unexpected streamed text %q", streamed)
+	}
+	if u, ok := client.Usage(); !ok || u.PromptTokens != 152 || u.CompletionTokens != 21 {
+		t.Errorf("unexpected usage %+v, %v", u, ok)
+	}
+}
+
+func TestReplay_AnthropicRetry(t *testing.T) {
+	client := replayClient(t, "anthropic.json", "https://api.anthropic.com/v1/messages", config.ProviderAnthropic)
+	client.cfg.MaxRetries = 1
+	client.cfg.RetryBaseDelay = "1ms"
+
+	// The recorded session was overloaded once before answering.
+	cmd, err := client.GenerateCommand(testContext(t), "five largest folders here", "linux")
+	if err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if cmd != "du -sh ./* | sort -rh | head -n 5" {
+		t.Errorf("unexpected command %q", cmd)
+	}
+	if discarded := client.Discarded(); len(discarded) != 1 || discarded[0] != "Here is the command:" {
+		t.Errorf("expected the introduction to be discarded, got %q", discarded)
+	}
+
+	// A request the cassette doesn't have fails at once, without falling
+	// back to the offline library.
+	client.cfg.OfflineFallback = true
+	_, err = client.GenerateCommand(testContext(t), "show free disk space", "linux")
+	if !errors.Is(err, cassette.ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+}
+
+func TestNewClient_CassetteFromEnv(t *testing.T) {
+	t.Setenv(cassette.EnvPath, filepath.Join(t.TempDir(), "missing.json"))
+	t.Setenv(cassette.EnvMode, "replay")
+
+	client := newTestClient("https://api.openai.com/v1/chat/completions", config.ProviderOpenAI)
+	if _, err := client.GenerateCommand(testContext(t), "list files", "linux"); err == nil {
+		t.Error("expected a missing cassette to fail every request")
+	}
+	if err := client.TestConnection(testContext(t)); err == nil {
+		t.Error("expected a missing cassette to fail the connection test")
+	}
+}
@@ -1,65 +1,105 @@
 package ai
 
 import (
-	"bytes"
+	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"runtime"
 	"strings"
 	"time"
 
+	"aiterm/internal/cassette"
 	"aiterm/internal/config"
+	"aiterm/internal/distro"
+	"aiterm/internal/envctx"
+	"aiterm/internal/examples"
+	"aiterm/internal/prompt"
+	"aiterm/internal/shell"
+	"aiterm/internal/tools"
+	"aiterm/internal/transport"
 )
 
-// Client handles communication with an OpenAI-compatible API.
+// Client handles communication with the configured AI provider.
 type Client struct {
 	cfg        *config.Config
 	httpClient *http.Client
+	answeredBy *config.Endpoint
+	last       *Exchange
+	usage      *Usage
+	discarded  []string
+	cached     bool
+	offline    *OfflineAnswer
+	partial    string
+
+	// environment and toolHint cache what is appended to system prompts.
+	environment *string
+	toolHint    *string
+
+	// library caches the few-shot examples once loaded.
+	library *[]examples.Example
+
+	// transportErr is set when the transport settings or the cassette
+	// named in the environment cannot be used; every request fails with it.
+	transportErr error
 }
 
-// NewClient creates a new AI client from the given configuration.
+// NewClient creates a new AI client from the given configuration. Requests
+// go through the configured proxy, CAs, client certificate and pins. When
+// cassette.EnvPath is set, they are recorded to or replayed from that
+// cassette, with the configured API tokens redacted.
 func NewClient(cfg *config.Config) *Client {
+	rt, err := transport.New(cfg)
+	if err == nil {
+		var secrets []string
+		for _, ep := range cfg.EndpointChain() {
+			secrets = append(secrets, ep.APIToken)
+		}
+		rt, err = cassette.FromEnv(rt, secrets...)
+	}
+
 	return &Client{
 		cfg: cfg,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   cfg.TimeoutDuration(),
+			Transport: rt,
 		},
+		transportErr: err,
 	}
 }
 
-// chatRequest represents the request body for the chat completions API.
-type chatRequest struct {
-	Model    string        `json:"model"`
-	Messages []chatMessage `json:"messages"`
+// SetTransport replaces the transport that carries API requests, such as
+// with a cassette.Transport in tests.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+	c.transportErr = nil
 }
 
-// chatMessage represents a single message in the chat history.
-type chatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// AnsweredBy returns the endpoint that served the most recent successful
+// request. ok is false if no request has succeeded yet.
+func (c *Client) AnsweredBy() (ep config.Endpoint, ok bool) {
+	if c.answeredBy == nil {
+		return config.Endpoint{}, false
+	}
+	return *c.answeredBy, true
 }
 
-// chatResponse represents the response body from the chat completions API.
-type chatResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-	Error *struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-		Code    string `json:"code"`
-	} `json:"error,omitempty"`
+// Partial returns the text that had streamed in before the most recent
+// streamed request failed or was interrupted, or "".
+func (c *Client) Partial() string {
+	return c.partial
 }
 
-// ResolveTargetOS maps a user-provided -t flag to a full OS name.
-// If empty or "auto", it detects the current OS.
+// ResolveTargetOS maps a user-provided -t flag to a full OS name and that
+// OS's default shell. If empty or "auto", it detects the current OS. A
+// distribution suffix such as "linux/alpine" is accepted and ignored here;
+// see ResolveDistro.
 func ResolveTargetOS(target string) (osName string, shellType string) {
-	switch strings.ToLower(target) {
+	osPart, _, _ := strings.Cut(strings.ToLower(target), "/")
+	switch osPart {
 	case "win", "windows":
 		return "Windows", "PowerShell"
 	case "linux":
@@ -79,6 +119,95 @@ func ResolveTargetOS(target string) (osName string, shellType string) {
 	}
 }
 
+// detectDistro reads the running system's os-release; tests replace it.
+var detectDistro = distro.Detect
+
+// ResolveDistro returns the Linux distribution to generate commands for:
+// the one named by a -t value such as "linux/alpine", or the running one
+// when targeting this Linux machine. ok is false for other OSes and when
+// the distribution is unknown.
+func ResolveDistro(target string) (info distro.Info, ok bool) {
+	if osName, _ := ResolveTargetOS(target); osName != "Linux" {
+		return distro.Info{}, false
+	}
+	if _, id, found := strings.Cut(target, "/"); found {
+		id = strings.ToLower(strings.TrimSpace(id))
+		return distro.Info{ID: id}, id != ""
+	}
+	if localOS, _ := ResolveTargetOS(""); localOS != "Linux" {
+		return distro.Info{}, false
+	}
+	return detectDistro()
+}
+
+// IsLocalTarget reports whether commands for target can run on this
+// machine: same OS and, if -t names one, the same distribution.
+func IsLocalTarget(target string) bool {
+	osName, _ := ResolveTargetOS(target)
+	if localOS, _ := ResolveTargetOS(""); osName != localOS {
+		return false
+	}
+	if _, id, found := strings.Cut(target, "/"); found {
+		local, ok := detectDistro()
+		return ok && strings.EqualFold(local.ID, strings.TrimSpace(id))
+	}
+	return true
+}
+
+// distroHint tells the model which distribution and package manager to
+// use, or returns "" when the distribution is unknown.
+func distroHint(targetOS string) string {
+	info, ok := ResolveDistro(targetOS)
+	if !ok {
+		return ""
+	}
+	hint := fmt.Sprintf("The Linux distribution is %s.", info.Name())
+	if pm := info.PackageManager(); pm != "" {
+		hint += fmt.Sprintf(" Use %s for package management.", pm)
+	}
+	return hint
+}
+
+// detectShell finds the shell aiterm was started from; tests replace it.
+var detectShell = shell.Detect
+
+// ResolveTarget maps the -t flag and a preferred shell (the shell config key
+// or --shell) to an OS name and the display name of the shell to generate
+// for. A preferred shell that does not exist on the target OS is ignored.
+// Without a preference ("" or "auto"), commands for this machine target the
+// shell aiterm was started from.
+func ResolveTarget(target, shellName string) (osName, shellType string) {
+	osName, shellType = ResolveTargetOS(target)
+
+	if spec, ok := shell.Lookup(shellName); ok {
+		if spec.SupportsOS(osName) {
+			shellType = spec.Name
+		}
+		return osName, shellType
+	}
+
+	if IsLocalTarget(target) {
+		if spec, ok := detectShell(); ok && spec.SupportsOS(osName) {
+			shellType = spec.Name
+		}
+	}
+	return osName, shellType
+}
+
+// resolveTarget resolves targetOS with the configured shell preference.
+func (c *Client) resolveTarget(targetOS string) (osName, shellType string) {
+	return ResolveTarget(targetOS, c.cfg.Shell)
+}
+
+// shellGuidance returns the syntax notes for shellType, prefixed with a
+// space, or "" for shells without any.
+func shellGuidance(shellType string) string {
+	if spec, ok := shell.Lookup(shellType); ok && spec.Guidance != "" {
+		return " " + spec.Guidance
+	}
+	return ""
+}
+
 // systemPrompt builds the system prompt for the given OS and shell.
 func systemPrompt(osName, shellType string) string {
 	return fmt.Sprintf(
@@ -86,110 +215,489 @@ func systemPrompt(osName, shellType string) string {
 			"Return ONLY the command with no explanation, no markdown, no code blocks. "+
 			"The command should work in %s on %s.",
 		shellType, osName,
-	)
+	) + shellGuidance(shellType)
+}
+
+// Environment returns the environment context sent with generation
+// prompts, exactly as the model sees it, or "" if no source is enabled. It
+// is collected once per client.
+func (c *Client) Environment() string {
+	if c.environment == nil {
+		env := envctx.Collect(c.cfg.Context).String()
+		c.environment = &env
+	}
+	return *c.environment
+}
+
+// ToolHint returns the installed-tool hint sent with generation prompts
+// for the local OS, or "" if tool detection is disabled.
+func (c *Client) ToolHint() string {
+	if c.toolHint == nil {
+		var hint string
+		if c.cfg.DetectTools {
+			hint = tools.Detect(c.cfg.Tools, c.cfg.ToolsCacheTTLDuration()).Hint()
+		}
+		c.toolHint = &hint
+	}
+	return *c.toolHint
+}
+
+// withEnvironment appends the target's Linux distribution, the environment
+// context and, when generating for this machine, which tools are installed
+// to a system prompt.
+func (c *Client) withEnvironment(system, targetOS string) string {
+	if hint := distroHint(targetOS); hint != "" {
+		system += "\n\n" + hint
+	}
+	if IsLocalTarget(targetOS) {
+		if hint := c.ToolHint(); hint != "" {
+			system += "\n\n" + hint
+		}
+	}
+	if env := c.Environment(); env != "" {
+		system += "\n\n" + env
+	}
+	return system
 }
 
 // GenerateCommand sends a natural language description to the AI API and
 // returns the generated shell command. targetOS can be "win", "linux", "mac", or "" for auto.
+// When no endpoint can be reached, the offline library answers instead;
+// see Offline.
 func (c *Client) GenerateCommand(ctx context.Context, description, targetOS string) (string, error) {
 	if err := c.cfg.Validate(); err != nil {
 		return "", err
 	}
 
-	osName, shellType := ResolveTargetOS(targetOS)
+	req, err := c.commandRequest(description, targetOS, false)
+	if err != nil {
+		return "", err
+	}
+	key := c.cacheKey(req, description, targetOS, 1)
+	if commands, ok := c.fromCache(key); ok {
+		c.remember(description, targetOS, req.Messages, commands[0])
+		return commands[0], nil
+	}
+
+	choices, err := c.complete(ctx, req)
+	if err != nil {
+		commands, err := c.fallBackOffline(ctx, err, description, targetOS, 1)
+		if err != nil {
+			return "", err
+		}
+		return commands[0], nil
+	}
 
-	reqBody := chatRequest{
-		Model: c.cfg.Model,
-		Messages: []chatMessage{
-			{Role: "system", Content: systemPrompt(osName, shellType)},
-			{Role: "user", Content: fmt.Sprintf("Generate a single shell command for: %s", description)},
-		},
+	command := c.sanitize(choices[0])
+	if command == "" {
+		return "", errNoCommand
 	}
+	c.toCache(key, []string{command})
+	c.remember(description, targetOS, req.Messages, command)
+	return command, nil
+}
 
-	bodyBytes, err := json.Marshal(reqBody)
+// complete sends a non-streamed exchange and returns the reply text of
+// every choice in the response (at least one).
+func (c *Client) complete(ctx context.Context, creq completionRequest) ([]string, error) {
+	resp, p, err := c.send(ctx, creq)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.APIEndpoint, bytes.NewReader(bodyBytes))
+	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.cfg.APIToken)
+	u, ok := responseUsage(p, respBytes)
+	c.addUsage(u, ok, resp.Header)
 
-	resp, err := c.httpClient.Do(req)
+	if mp, ok := p.(multiChoiceProvider); ok {
+		return mp.parseChoices(respBytes)
+	}
+
+	content, err := p.parseResponse(respBytes)
 	if err != nil {
-		if ctx.Err() != nil {
-			return "", fmt.Errorf("request timed out")
+		return nil, err
+	}
+	return []string{content}, nil
+}
+
+// GenerateCommandStream works like GenerateCommand but requests a streamed
+// completion and calls onToken with every content delta as it arrives. The
+// returned command is the full, sanitized response. Cached answers are
+// returned without calling onToken.
+func (c *Client) GenerateCommandStream(ctx context.Context, description, targetOS string, onToken func(string)) (string, error) {
+	if err := c.cfg.Validate(); err != nil {
+		return "", err
+	}
+
+	req, err := c.commandRequest(description, targetOS, true)
+	if err != nil {
+		return "", err
+	}
+	key := c.cacheKey(req, description, targetOS, 1)
+	if commands, ok := c.fromCache(key); ok {
+		c.remember(description, targetOS, req.Messages, commands[0])
+		return commands[0], nil
+	}
+
+	content, err := c.stream(ctx, req, onToken)
+	if err != nil {
+		commands, err := c.fallBackOffline(ctx, err, description, targetOS, 1)
+		if err != nil {
+			return "", err
 		}
-		return "", fmt.Errorf("API request failed: %w", err)
+		return commands[0], nil
+	}
+
+	command := c.sanitize(content)
+	if command == "" {
+		return "", errNoCommand
+	}
+	c.toCache(key, []string{command})
+	c.remember(description, targetOS, req.Messages, command)
+	return command, nil
+}
+
+// stream sends a streamed exchange and returns the joined reply, calling
+// onToken for every content delta.
+func (c *Client) stream(ctx context.Context, creq completionRequest, onToken func(string)) (string, error) {
+	creq.Stream = true
+	c.partial = ""
+
+	resp, p, err := c.send(ctx, creq)
+	if isRejectedParameter(err) && !creq.NoStreamUsage {
+		// Some compatible backends reject stream_options; usage is then
+		// simply not reported.
+		creq.NoStreamUsage = true
+		resp, p, err = c.send(ctx, creq)
+	}
+	if err != nil {
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	respBytes, err := io.ReadAll(resp.Body)
+	var content string
+	var u Usage
+	var reported bool
+	if isStreamResponse(resp) {
+		content, u, reported, err = readStream(resp.Body, p, onToken)
+	} else {
+		// Some backends ignore "stream" and answer with a regular completion.
+		var respBytes []byte
+		respBytes, err = io.ReadAll(resp.Body)
+		if err == nil {
+			content, err = p.parseResponse(respBytes)
+			u, reported = responseUsage(p, respBytes)
+		}
+		if err == nil && onToken != nil {
+			onToken(content)
+		}
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		c.partial = content
+		if ctx.Err() != nil {
+			return "", &ctxError{err: ctx.Err()}
+		}
+		return "", err
 	}
 
-	// Handle HTTP error codes
-	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		return "", fmt.Errorf("authentication failed — check your API token")
-	case http.StatusTooManyRequests:
-		return "", fmt.Errorf("rate limit exceeded — please try again later")
-	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable:
-		return "", fmt.Errorf("API server error (HTTP %d)", resp.StatusCode)
+	c.addUsage(u, reported, resp.Header)
+	return content, nil
+}
+
+// commandRequest builds the exchange used to generate a command.
+func (c *Client) commandRequest(description, targetOS string, stream bool) (completionRequest, error) {
+	osName, shellType := c.resolveTarget(targetOS)
+
+	system, err := c.commandSystem(targetOS)
+	if err != nil {
+		return completionRequest{}, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned HTTP %d: %s", resp.StatusCode, string(respBytes))
+	shots, err := c.examples(description, osName, shellType)
+	if err != nil {
+		return completionRequest{}, err
 	}
 
-	var chatResp chatResponse
-	if err := json.Unmarshal(respBytes, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse API response: %w", err)
+	messages := []Message{{Role: "system", Content: system}}
+	for _, e := range shots {
+		messages = append(messages,
+			Message{Role: "user", Content: commandInstruction(e.Prompt)},
+			Message{Role: "assistant", Content: e.Command},
+		)
 	}
+	messages = append(messages, Message{Role: "user", Content: commandInstruction(description)})
+
+	return completionRequest{Messages: messages, Stream: stream}, nil
+}
 
-	// Check for API-level error in response body
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s", chatResp.Error.Message)
+// commandSystem builds the system prompt for generating a command for
+// targetOS, with the distribution, installed tools and environment context.
+func (c *Client) commandSystem(targetOS string) (string, error) {
+	osName, shellType := c.resolveTarget(targetOS)
+	system, err := c.generationPrompt(systemPrompt(osName, shellType), osName, shellType, targetOS)
+	if err != nil {
+		return "", err
 	}
+	return c.withEnvironment(system, targetOS), nil
+}
 
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("API returned no choices")
+// commandInstruction is the user turn asking for a command.
+func commandInstruction(description string) string {
+	return fmt.Sprintf("Generate a single shell command for: %s", description)
+}
+
+// examples returns the configured number of few-shot examples most
+// relevant to description, loading the library on first use.
+func (c *Client) examples(description, osName, shellType string) ([]examples.Example, error) {
+	if c.cfg.Examples <= 0 {
+		return nil, nil
+	}
+	if c.library == nil {
+		library, err := examples.Load()
+		if err != nil {
+			return nil, err
+		}
+		c.library = &library
 	}
+	return examples.Select(*c.library, description, osName, shellType, c.cfg.Examples), nil
+}
 
-	command := strings.TrimSpace(chatResp.Choices[0].Message.Content)
+// PromptMessages returns the messages a generation request for description
+// would send, without sending them.
+func (c *Client) PromptMessages(description, targetOS string) ([]Message, error) {
+	req, err := c.commandRequest(description, targetOS, false)
+	if err != nil {
+		return nil, err
+	}
+	return req.Messages, nil
+}
 
-	// Strip markdown code fences if the model returned them anyway
-	command = stripCodeFences(command)
+// generationPrompt returns the system prompt for generating commands: the
+// built-in one, or the configured prompt_template rendered with builtin as
+// its .Default.
+func (c *Client) generationPrompt(builtin, osName, shellType, targetOS string) (string, error) {
+	if c.cfg.PromptTemplate == "" {
+		return builtin, nil
+	}
 
-	return command, nil
+	data := prompt.Data{
+		OS:      osName,
+		Shell:   shellType,
+		Vars:    c.cfg.PromptVars,
+		Default: builtin,
+	}
+	if info, ok := ResolveDistro(targetOS); ok {
+		data.Distro = info.Name()
+		data.PackageManager = info.PackageManager()
+	}
+	data.Cwd, _ = os.Getwd()
+
+	return prompt.Render(c.cfg.PromptTemplate, data)
 }
 
-// TestConnection verifies that the API endpoint and token are working.
-func (c *Client) TestConnection(ctx context.Context) error {
-	reqBody := chatRequest{
-		Model: c.cfg.Model,
-		Messages: []chatMessage{
-			{Role: "user", Content: "Reply with exactly: ok"},
-		},
+// send posts an exchange to the endpoint chain, falling through to the next
+// endpoint on connection errors, server errors and authentication failures.
+// It returns the response together with the provider that produced it; on
+// success the caller must close the response body.
+func (c *Client) send(ctx context.Context, creq completionRequest) (*http.Response, provider, error) {
+	chain := c.cfg.EndpointChain()
+
+	// With a single endpoint there is nothing to fail over to or remember.
+	if len(chain) == 1 {
+		p := newProvider(chain[0])
+		resp, err := c.sendWithRetry(ctx, p, creq)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.answeredBy = &chain[0]
+		return resp, p, nil
 	}
 
-	bodyBytes, err := json.Marshal(reqBody)
+	health := loadHealth()
+	now := time.Now()
+
+	var lastErr error
+	for _, ep := range health.order(chain, now) {
+		p := newProvider(ep)
+		resp, err := c.sendWithRetry(ctx, p, creq)
+		if err == nil {
+			health.markHealthy(ep)
+			health.save()
+			c.answeredBy = &ep
+			return resp, p, nil
+		}
+
+		lastErr = err
+		if !shouldFailover(err) {
+			break
+		}
+		health.markFailed(ep, now.Add(c.cfg.EndpointCooldownDuration()))
+	}
+
+	health.save()
+	if shouldFailover(lastErr) {
+		return nil, nil, fmt.Errorf("all %d endpoints failed, last error: %w", len(chain), lastErr)
+	}
+	return nil, nil, lastErr
+}
+
+// sendWithRetry posts an exchange through p, retrying transient failures
+// according to the configured retry policy.
+func (c *Client) sendWithRetry(ctx context.Context, p provider, creq completionRequest) (*http.Response, error) {
+	policy := newRetryPolicy(c.cfg)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.sendOnce(ctx, p, creq)
+		if err == nil {
+			return resp, nil
+		}
+
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) || !apiErr.transient() || attempt >= policy.maxRetries {
+			return nil, err
+		}
+
+		if !sleepContext(ctx, policy.delay(attempt, apiErr.retryAfter)) {
+			// Interrupted or timed out while waiting; the API error only
+			// stands when the wait would have outlasted the deadline.
+			if ctx.Err() != nil {
+				return nil, &ctxError{err: ctx.Err()}
+			}
+			return nil, err
+		}
+	}
+}
+
+// sendOnce performs a single request and maps HTTP error codes to errors.
+func (c *Client) sendOnce(ctx context.Context, p provider, creq completionRequest) (*http.Response, error) {
+	if c.transportErr != nil {
+		return nil, c.transportErr
+	}
+	req, err := p.newRequest(ctx, creq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.APIEndpoint, bytes.NewReader(bodyBytes))
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		if ctx.Err() != nil {
+			return nil, &ctxError{err: ctx.Err()}
+		}
+		// A replay miss is final: retrying or failing over won't change it.
+		if errors.Is(err, cassette.ErrNoMatch) {
+			return nil, err
+		}
+		return nil, &apiError{msg: "API request failed", err: err}
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &apiError{status: resp.StatusCode, retryAfter: retryAfter(resp.Header, time.Now())}
+
+	// Handle HTTP error codes
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		apiErr.msg = "authentication failed — check your API token"
+	case http.StatusTooManyRequests:
+		apiErr.msg = "rate limit exceeded — please try again later"
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		apiErr.msg = fmt.Sprintf("API server error (HTTP %d)", resp.StatusCode)
+	default:
+		respBytes, _ := io.ReadAll(resp.Body)
+		apiErr.msg = fmt.Sprintf("API returned HTTP %d: %s", resp.StatusCode, string(respBytes))
+	}
+	return nil, apiErr
+}
+
+// errNoCommand is returned when nothing of the reply is left after
+// sanitizing, such as for a reply with only a reasoning block.
+var errNoCommand = errors.New("API returned no command")
+
+// ctxError is returned when the context ends a request. It wraps the
+// context's error, so that callers can tell an interruption
+// (context.Canceled) from a timeout (context.DeadlineExceeded).
+type ctxError struct {
+	err error
+}
+
+func (e *ctxError) Error() string {
+	if errors.Is(e.err, context.Canceled) {
+		return "request cancelled"
+	}
+	return "request timed out"
+}
+
+func (e *ctxError) Unwrap() error {
+	return e.err
+}
+
+// isStreamResponse reports whether resp carries server-sent events or
+// newline-delimited JSON rather than a single JSON document.
+func isStreamResponse(resp *http.Response) bool {
+	contentType := resp.Header.Get("Content-Type")
+	return strings.Contains(contentType, "event-stream") || strings.Contains(contentType, "ndjson")
+}
+
+// readStream consumes a streamed response line by line, calling onToken for
+// each content delta, and returns the joined content together with the
+// token usage reported along the way. reported is false if there was none.
+// When reading fails, content is what arrived before.
+func readStream(r io.Reader, p provider, onToken func(string)) (content string, u Usage, reported bool, err error) {
+	var text strings.Builder
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		delta, done, err := p.parseStreamLine(line)
+		if err != nil {
+			return "", Usage{}, false, err
+		}
+		if delta != "" {
+			text.WriteString(delta)
+			if onToken != nil {
+				onToken(delta)
+			}
+		}
+		if lineUsage, ok := streamUsage(p, line); ok {
+			u.merge(lineUsage)
+			reported = true
+		}
+		if done {
+			return text.String(), u, reported, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// Keep what arrived, for callers showing partial output.
+		return text.String(), Usage{}, false, fmt.Errorf("failed to read stream: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.cfg.APIToken)
+	// The stream ended without an end marker; keep what arrived.
+	return text.String(), u, reported, nil
+}
+
+// TestConnection verifies that the API endpoint and token are working.
+func (c *Client) TestConnection(ctx context.Context) error {
+	if c.transportErr != nil {
+		return c.transportErr
+	}
+	p := newProvider(c.cfg.EndpointChain()[0])
+	req, err := p.newRequest(ctx, completionRequest{
+		Messages: []Message{
+			{Role: "user", Content: "Reply with exactly: ok"},
+		},
+	})
+	if err != nil {
+		return err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -207,12 +715,3 @@ func (c *Client) TestConnection(ctx context.Context) error {
 
 	return nil
 }
-
-// stripCodeFences removes markdown code block fences from a string.
-func stripCodeFences(s string) string {
-	lines := strings.Split(s, "\n")
-	if len(lines) >= 2 && strings.HasPrefix(lines[0], "```") && strings.HasSuffix(lines[len(lines)-1], "```") {
-		lines = lines[1 : len(lines)-1]
-	}
-	return strings.TrimSpace(strings.Join(lines, "\n"))
-}
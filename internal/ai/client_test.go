@@ -3,12 +3,20 @@ package ai
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"aiterm/internal/config"
+	"aiterm/internal/distro"
+	"aiterm/internal/shell"
 )
 
 func TestGenerateCommand_Success(t *testing.T) {
@@ -120,26 +128,6 @@ func TestGenerateCommand_ValidationError(t *testing.T) {
 	}
 }
 
-func TestStripCodeFences(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"ls -la", "ls -la"},
-		{"```\nls -la\n```", "ls -la"},
-		{"```bash\nls -la\n```", "ls -la"},
-		{"```sh\nfind . -name '*.go'\n```", "find . -name '*.go'"},
-		{"no fences here", "no fences here"},
-	}
-
-	for _, tt := range tests {
-		result := stripCodeFences(tt.input)
-		if result != tt.expected {
-			t.Errorf("stripCodeFences(%q) = %q, want %q", tt.input, result, tt.expected)
-		}
-	}
-}
-
 func TestTestConnection_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := map[string]interface{}{
@@ -166,6 +154,96 @@ func TestTestConnection_Success(t *testing.T) {
 	}
 }
 
+func TestResolveTarget(t *testing.T) {
+	// Pretend aiterm was started from fish.
+	detectShell = func() (shell.Spec, bool) { return shell.Lookup("fish") }
+	t.Cleanup(func() { detectShell = shell.Detect })
+
+	localOS, localDefault := ResolveTargetOS("")
+	otherTarget, otherOS := "win", "Windows"
+	if localOS == "Windows" {
+		otherTarget, otherOS = "linux", "Linux"
+	}
+	_, otherDefault := ResolveTargetOS(otherTarget)
+
+	tests := []struct {
+		target        string
+		shell         string
+		expectedOS    string
+		expectedShell string
+	}{
+		{"", "pwsh", localOS, "PowerShell 7"},
+		{otherTarget, "pwsh", otherOS, "PowerShell 7"},
+		{"linux", "nushell", "Linux", "Nushell"},
+		{"linux", "sh", "Linux", "POSIX sh"},
+		{"win", "cmd", "Windows", "cmd.exe"},
+		// A shell that does not exist on the target falls back to its default.
+		{"win", "fish", "Windows", "PowerShell"},
+		{"linux", "cmd", "Linux", "bash"},
+		// Other OSes never use the detected local shell.
+		{otherTarget, "auto", otherOS, otherDefault},
+	}
+	for _, tt := range tests {
+		osName, shellType := ResolveTarget(tt.target, tt.shell)
+		if osName != tt.expectedOS || shellType != tt.expectedShell {
+			t.Errorf("ResolveTarget(%q, %q) = %q, %q; want %q, %q", tt.target, tt.shell, osName, shellType, tt.expectedOS, tt.expectedShell)
+		}
+	}
+
+	if localOS != "Windows" {
+		if _, shellType := ResolveTarget("", "auto"); shellType != "fish" {
+			t.Errorf("expected the detected shell on auto, got %q", shellType)
+		}
+	} else if _, shellType := ResolveTarget("", ""); shellType != localDefault {
+		t.Errorf("expected %q on Windows, got %q", localDefault, shellType)
+	}
+}
+
+func TestResolveDistro(t *testing.T) {
+	detectDistro = func() (distro.Info, bool) {
+		return distro.Info{ID: "fedora", VersionID: "39", PrettyName: "Fedora Linux 39"}, true
+	}
+	t.Cleanup(func() { detectDistro = distro.Detect })
+
+	if osName, shellType := ResolveTargetOS("linux/alpine"); osName != "Linux" || shellType != "bash" {
+		t.Errorf("ResolveTargetOS(linux/alpine) = %q, %q; want Linux, bash", osName, shellType)
+	}
+
+	info, ok := ResolveDistro("linux/Alpine")
+	if !ok || info.ID != "alpine" || info.PackageManager() != "apk" {
+		t.Errorf("ResolveDistro(linux/Alpine) = %+v, %v", info, ok)
+	}
+	if _, ok := ResolveDistro("mac"); ok {
+		t.Error("macOS has no Linux distribution")
+	}
+	if hint := distroHint("linux/arch"); hint != "The Linux distribution is arch. Use pacman for package management." {
+		t.Errorf("unexpected hint: %q", hint)
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	if info, ok := ResolveDistro(""); !ok || info.ID != "fedora" {
+		t.Errorf("expected the running distribution on auto, got %+v, %v", info, ok)
+	}
+	if !IsLocalTarget("linux/fedora") || IsLocalTarget("linux/alpine") || !IsLocalTarget("") {
+		t.Error("only the running distribution is local")
+	}
+	if hint := distroHint(""); hint != "The Linux distribution is Fedora Linux 39. Use dnf for package management." {
+		t.Errorf("unexpected hint: %q", hint)
+	}
+}
+
+func TestSystemPrompt_ShellGuidance(t *testing.T) {
+	prompt := systemPrompt("Linux", "fish")
+	if !strings.Contains(prompt, "work in fish on Linux") || !strings.Contains(prompt, "set VAR value") {
+		t.Errorf("expected fish guidance in prompt:\n%s", prompt)
+	}
+	if prompt := systemPrompt("Windows", "PowerShell"); !strings.Contains(prompt, "NOT available") {
+		t.Errorf("expected Windows PowerShell guidance in prompt:\n%s", prompt)
+	}
+}
+
 func TestResolveTargetOS(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -196,3 +274,342 @@ func TestResolveTargetOS(t *testing.T) {
 		t.Errorf("ResolveTargetOS(\"\") returned empty values: os=%q shell=%q", osName, shellType)
 	}
 }
+
+func TestGenerateCommandStream_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if !req.Stream {
+			t.Error("expected stream: true in request body")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{"```bash\n", "ls", " -la", "\n```"} {
+			chunk, _ := json.Marshal(map[string]interface{}{
+				"choices": []map[string]interface{}{
+					{"delta": map[string]string{"content": token}},
+				},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIEndpoint: server.URL,
+		APIToken:    "test-token",
+		Model:       "gpt-4o-mini",
+	}
+
+	client := NewClient(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var tokens []string
+	cmd, err := client.GenerateCommandStream(ctx, "list all files", "", func(token string) {
+		tokens = append(tokens, token)
+	})
+	if err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+
+	if cmd != "ls -la" {
+		t.Errorf("unexpected command: %q, want %q", cmd, "ls -la")
+	}
+	if len(tokens) != 4 {
+		t.Errorf("expected 4 tokens, got %d: %q", len(tokens), tokens)
+	}
+}
+
+func TestGenerateCommandStream_Interrupted(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"find . -name\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		// Stall until the client gives up.
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	client.cfg.OfflineFallback = true
+	ctx, cancel := context.WithCancel(testContext(t))
+	defer cancel()
+
+	// Interrupt as soon as the first token arrives, as Ctrl-C would.
+	_, err := client.GenerateCommandStream(ctx, "find go files", "linux", func(string) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if client.Partial() != "find . -name" {
+		t.Errorf("Partial() = %q", client.Partial())
+	}
+	if _, ok := client.Offline(); ok {
+		t.Error("expected an interruption not to fall back to the offline library")
+	}
+}
+
+func TestGenerateCommand_Interrupted(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		// Stall until the client gives up.
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	ctx, cancel := context.WithCancel(testContext(t))
+	defer cancel()
+
+	// Interrupt while the request is in flight, as Ctrl-C would.
+	go func() {
+		<-requested
+		cancel()
+	}()
+
+	_, err := client.GenerateCommand(ctx, "list files", "linux")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestGenerateCommandStream_InterruptedDuringBackoff(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		requested <- struct{}{}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	client.cfg.MaxRetries = 3
+	client.cfg.OfflineFallback = true
+	ctx, cancel := context.WithCancel(testContext(t))
+	defer cancel()
+
+	// Interrupt while the client waits out Retry-After.
+	go func() {
+		<-requested
+		cancel()
+	}()
+
+	_, err := client.GenerateCommandStream(ctx, "find go files", "linux", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := client.Offline(); ok {
+		t.Error("expected an interruption not to fall back to the offline library")
+	}
+}
+
+func TestGenerateCommandStream_StreamOptionsRejected(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.StreamOptions != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": {"message": "Unrecognized request argument supplied: stream_options"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"uptime\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	cmd, err := newTestClient(server.URL, config.ProviderOpenAI).GenerateCommandStream(testContext(t), "how long is the system up", "", nil)
+	if err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+	if cmd != "uptime" || calls != 2 {
+		t.Errorf("got %q after %d calls, want %q after 2", cmd, calls, "uptime")
+	}
+}
+
+func TestGenerateCommandStream_NonStreamingFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "pwd"}},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIEndpoint: server.URL,
+		APIToken:    "test-token",
+		Model:       "gpt-4o-mini",
+	}
+
+	client := NewClient(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var streamed string
+	cmd, err := client.GenerateCommandStream(ctx, "current directory", "", func(token string) {
+		streamed += token
+	})
+	if err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+	if cmd != "pwd" || streamed != "pwd" {
+		t.Errorf("got command %q, streamed %q; want %q", cmd, streamed, "pwd")
+	}
+}
+
+func TestReadStream_ErrorChunk(t *testing.T) {
+	stream := "data: {\"error\": {\"message\": \"model overloaded\"}}\n\n"
+	if _, _, _, err := readStream(strings.NewReader(stream), &openAIProvider{}, nil); err == nil {
+		t.Fatal("expected error for error chunk")
+	}
+}
+
+func TestGenerateCommand_EnvironmentContext(t *testing.T) {
+	var system string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body chatRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		system = body.Messages[0].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ls"}}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	if _, err := client.GenerateCommand(testContext(t), "list", ""); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if strings.Contains(system, "Environment") {
+		t.Errorf("context sent without being enabled:\n%s", system)
+	}
+
+	client = newTestClient(server.URL, config.ProviderOpenAI)
+	client.cfg.Context.Git = true
+	if _, err := client.GenerateCommand(testContext(t), "list", ""); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if env := client.Environment(); env == "" || !strings.HasSuffix(system, "\n\n"+env) {
+		t.Errorf("expected the system prompt to end with the environment context %q, got:\n%s", env, system)
+	}
+}
+
+func TestGenerateCommand_ToolHint(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	var system string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body chatRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		system = body.Messages[0].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ls"}}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	client.cfg.DetectTools = true
+	client.cfg.Tools = []string{"aiterm-no-such-tool"}
+	if _, err := client.GenerateCommand(testContext(t), "list", ""); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if !strings.Contains(system, "Not installed (do not use): ") || !strings.Contains(system, "aiterm-no-such-tool") {
+		t.Errorf("expected the missing tool in the system prompt:\n%s", system)
+	}
+
+	// Tools on this machine say nothing about another OS.
+	target := "win"
+	if runtime.GOOS == "windows" {
+		target = "linux"
+	}
+	if _, err := client.GenerateCommand(testContext(t), "list", target); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if strings.Contains(system, "aiterm-no-such-tool") {
+		t.Errorf("tool hints must not be sent for another OS:\n%s", system)
+	}
+}
+
+func TestPromptMessages_Template(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	os.WriteFile(path, []byte("{{.Default}}\nTarget {{.Shell}} on {{.OS}} ({{.Distro}}, {{.PackageManager}}). Team {{.Vars.team}}: never use sudo."), 0600)
+
+	client := newTestClient("http://unused", config.ProviderOpenAI)
+	client.cfg.Shell = "fish"
+	client.cfg.PromptTemplate = path
+	client.cfg.PromptVars = map[string]string{"team": "infra"}
+
+	messages, err := client.PromptMessages("install htop", "linux/alpine")
+	if err != nil {
+		t.Fatalf("PromptMessages failed: %v", err)
+	}
+	system := messages[0].Content
+	for _, want := range []string{
+		systemPrompt("Linux", "fish"),
+		"Target fish on Linux (alpine, apk). Team infra: never use sudo.",
+	} {
+		if !strings.Contains(system, want) {
+			t.Errorf("system prompt missing %q:\n%s", want, system)
+		}
+	}
+	if messages[1].Content != "Generate a single shell command for: install htop" {
+		t.Errorf("unexpected user message: %q", messages[1].Content)
+	}
+
+	// Rendering errors fail the request instead of sending a broken prompt.
+	client.cfg.PromptVars = nil
+	if _, err := client.GenerateCommand(testContext(t), "install htop", "linux"); err == nil {
+		t.Error("expected error for a missing template variable")
+	}
+}
+
+func TestPromptMessages_Examples(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	client := newTestClient("http://unused", config.ProviderOpenAI)
+	client.cfg.Shell = "bash"
+	client.cfg.Examples = 2
+
+	messages, err := client.PromptMessages("list files larger than 1GB", "linux")
+	if err != nil {
+		t.Fatalf("PromptMessages failed: %v", err)
+	}
+	if len(messages) != 6 {
+		t.Fatalf("expected system, 2 example turns and the request, got %d messages", len(messages))
+	}
+	if messages[3].Role != "user" || messages[4].Role != "assistant" || messages[4].Content != "find . -type f -size +100M" {
+		t.Errorf("expected the most similar example right before the request, got %+v", messages[3:5])
+	}
+	if messages[5].Content != "Generate a single shell command for: list files larger than 1GB" {
+		t.Errorf("unexpected user message: %q", messages[5].Content)
+	}
+
+	client.cfg.Examples = 0
+	if messages, _ := client.PromptMessages("list files larger than 1GB", "linux"); len(messages) != 2 {
+		t.Errorf("expected no examples with examples=0, got %d messages", len(messages))
+	}
+}
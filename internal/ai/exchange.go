@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxRefineTurns bounds how many earlier messages (besides the system
+// prompt) are sent with a follow-up, so long sessions stay cheap.
+const maxRefineTurns = 10
+
+// Exchange is a finished generation: the original prompt, the resolved
+// command and the conversation that produced it. It can be saved and later
+// refined with follow-up requests.
+type Exchange struct {
+	Prompt   string    `json:"prompt"`
+	Command  string    `json:"command"`
+	Target   string    `json:"target"`
+	Messages []Message `json:"messages"`
+}
+
+// Choose replaces the exchange's command, for example with the candidate
+// the user picked among alternatives, so a follow-up builds on it.
+func (e *Exchange) Choose(command string) {
+	e.Command = command
+	if n := len(e.Messages); n > 0 && e.Messages[n-1].Role == "assistant" {
+		e.Messages[n-1].Content = command
+	}
+}
+
+// LastExchange returns the most recent successful generation or
+// refinement. ok is false if there was none.
+func (c *Client) LastExchange() (ex *Exchange, ok bool) {
+	return c.last, c.last != nil
+}
+
+// remember records a successful exchange for LastExchange.
+func (c *Client) remember(prompt, target string, messages []Message, command string) {
+	history := make([]Message, len(messages), len(messages)+1)
+	copy(history, messages)
+
+	c.last = &Exchange{
+		Prompt:   prompt,
+		Command:  command,
+		Target:   target,
+		Messages: append(history, Message{Role: "assistant", Content: command}),
+	}
+}
+
+// RefineCommand sends the conversation of prev plus a follow-up request,
+// so that corrections such as "now only in /var" build on the previous
+// command instead of starting over. The system prompt is rebuilt for
+// prev.Target and the configured shell, which may differ from the original
+// request's. Tokens are passed to onToken as they stream in; the returned
+// command is sanitized.
+func (c *Client) RefineCommand(ctx context.Context, prev *Exchange, followUp string, onToken func(string)) (string, error) {
+	if err := c.cfg.Validate(); err != nil {
+		return "", err
+	}
+	if prev == nil || len(prev.Messages) == 0 {
+		return "", fmt.Errorf("no previous command to refine")
+	}
+
+	followUp = strings.TrimSpace(followUp)
+	if followUp == "" {
+		return "", fmt.Errorf("no follow-up request given")
+	}
+
+	system, err := c.commandSystem(prev.Target)
+	if err != nil {
+		return "", err
+	}
+
+	messages := append(withSystem(trimHistory(prev.Messages), system), Message{
+		Role:    "user",
+		Content: fmt.Sprintf("Refine the previous command: %s\nReturn ONLY the updated command.", followUp),
+	})
+
+	content, err := c.stream(ctx, completionRequest{Messages: messages}, onToken)
+	if err != nil {
+		return "", err
+	}
+
+	command := c.sanitize(content)
+	if command == "" {
+		return "", errNoCommand
+	}
+	c.remember(prev.Prompt, prev.Target, messages, command)
+	return command, nil
+}
+
+// withSystem replaces the system messages of messages with system.
+func withSystem(messages []Message, system string) []Message {
+	replaced := []Message{{Role: "system", Content: system}}
+	for _, m := range messages {
+		if m.Role != "system" {
+			replaced = append(replaced, m)
+		}
+	}
+	return replaced
+}
+
+// trimHistory keeps the system prompt and the last maxRefineTurns messages.
+func trimHistory(messages []Message) []Message {
+	var system, rest []Message
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m)
+			continue
+		}
+		rest = append(rest, m)
+	}
+	if len(rest) > maxRefineTurns {
+		rest = rest[len(rest)-maxRefineTurns:]
+	}
+
+	trimmed := make([]Message, 0, len(system)+len(rest)+1)
+	trimmed = append(trimmed, system...)
+	return append(trimmed, rest...)
+}
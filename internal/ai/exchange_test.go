@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestRefineCommand(t *testing.T) {
+	var requests []chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		requests = append(requests, req)
+
+		content := "find . -size +100M"
+		if len(requests) == 2 {
+			content = "find /var -size +100M"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	if _, err := client.GenerateCommand(testContext(t), "list big files", "linux"); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	prev, ok := client.LastExchange()
+	if !ok || prev.Command != "find . -size +100M" || prev.Target != "linux" {
+		t.Fatalf("unexpected exchange: %+v", prev)
+	}
+
+	cmd, err := client.RefineCommand(testContext(t), prev, "now only in /var", nil)
+	if err != nil {
+		t.Fatalf("RefineCommand failed: %v", err)
+	}
+	if cmd != "find /var -size +100M" {
+		t.Errorf("unexpected command: %q", cmd)
+	}
+
+	// The follow-up must carry the whole previous conversation.
+	msgs := requests[1].Messages
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 messages in follow-up, got %d", len(msgs))
+	}
+	if msgs[2].Role != "assistant" || msgs[2].Content != "find . -size +100M" {
+		t.Errorf("expected previous answer as assistant turn, got %+v", msgs[2])
+	}
+	if !strings.Contains(msgs[3].Content, "now only in /var") {
+		t.Errorf("expected follow-up in last message, got %q", msgs[3].Content)
+	}
+
+	next, _ := client.LastExchange()
+	if next.Prompt != "list big files" || len(next.Messages) != 5 {
+		t.Errorf("expected refined exchange to extend the conversation, got %+v", next)
+	}
+}
+
+func TestRefineCommand_NoPrevious(t *testing.T) {
+	client := NewClient(&config.Config{APIEndpoint: "http://unused", APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	if _, err := client.RefineCommand(testContext(t), nil, "sort descending", nil); err == nil {
+		t.Fatal("expected error without a previous exchange")
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	messages := []Message{{Role: "system", Content: "sys"}}
+	for i := 0; i < 8; i++ {
+		messages = append(messages, Message{Role: "user", Content: "u"}, Message{Role: "assistant", Content: "a"})
+	}
+
+	trimmed := trimHistory(messages)
+	if len(trimmed) != maxRefineTurns+1 {
+		t.Fatalf("expected %d messages, got %d", maxRefineTurns+1, len(trimmed))
+	}
+	if trimmed[0].Role != "system" || trimmed[1].Role != "user" {
+		t.Errorf("expected system prompt followed by a user turn, got %+v", trimmed[:2])
+	}
+}
+
+func TestExchangeChoose(t *testing.T) {
+	ex := &Exchange{
+		Command:  "find . -name '*.go'",
+		Messages: []Message{{Role: "user", Content: "u"}, {Role: "assistant", Content: "find . -name '*.go'"}},
+	}
+	ex.Choose("fd -e go")
+	if ex.Command != "fd -e go" || ex.Messages[1].Content != "fd -e go" {
+		t.Errorf("Choose did not update the exchange: %+v", ex)
+	}
+}
+
+func TestRefineCommand_NewTarget(t *testing.T) {
+	var systems []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		systems = append(systems, req.Messages[0].Content)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "ls -la"}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+	if _, err := client.GenerateCommand(testContext(t), "list all files", "linux/alpine"); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	prev, _ := client.LastExchange()
+
+	// As with 'aiterm refine -t win', the follow-up is for another OS.
+	prev.Target = "win"
+	if _, err := client.RefineCommand(testContext(t), prev, "include hidden files", nil); err != nil {
+		t.Fatalf("RefineCommand failed: %v", err)
+	}
+
+	if len(systems) != 2 || !strings.Contains(systems[0], "on Linux") || !strings.Contains(systems[0], "apk") {
+		t.Fatalf("unexpected first system prompt %q", systems)
+	}
+	if !strings.Contains(systems[1], "on Windows") || strings.Contains(systems[1], "Linux") {
+		t.Errorf("expected the follow-up's system prompt to describe Windows, got %q", systems[1])
+	}
+}
@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Explanation is a breakdown of an existing shell command.
+type Explanation struct {
+	Summary     string    `json:"summary"`
+	Segments    []Segment `json:"segments"`
+	SideEffects []string  `json:"side_effects"`
+	RiskLevel   string    `json:"risk_level"`
+}
+
+// Segment explains one piece of a command: a program, flag, argument, pipe,
+// redirection or operator.
+type Segment struct {
+	Text        string `json:"text"`
+	Kind        string `json:"kind"`
+	Explanation string `json:"explanation"`
+}
+
+// explanationSchema is the JSON schema for Explanation.
+var explanationSchema = &jsonSchema{
+	Name: "command_explanation",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"summary": map[string]interface{}{"type": "string"},
+			"segments": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"text":        map[string]interface{}{"type": "string"},
+						"kind":        map[string]interface{}{"type": "string", "enum": []string{"command", "flag", "argument", "pipe", "redirection", "operator", "substitution", "other"}},
+						"explanation": map[string]interface{}{"type": "string"},
+					},
+					"required":             []string{"text", "kind", "explanation"},
+					"additionalProperties": false,
+				},
+			},
+			"side_effects": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"risk_level":   map[string]interface{}{"type": "string", "enum": []string{RiskSafe, RiskLow, RiskMedium, RiskHigh, RiskCritical}},
+		},
+		"required":             []string{"summary", "segments", "side_effects", "risk_level"},
+		"additionalProperties": false,
+	},
+}
+
+// explainSystemPrompt builds the system prompt for explaining a command.
+func explainSystemPrompt(osName, shellType string) string {
+	return fmt.Sprintf(
+		"You are a shell command explainer. The user gives you a command meant to run in %s on %s; "+
+			"interpret it with that shell's syntax and that platform's tools and flags. "+
+			"Respond with ONLY a JSON object, no markdown, with these fields: "+
+			`"summary" (string, what the whole command does), `+
+			`"segments" (array of objects in command order, one per program, flag, argument, pipe, redirection or operator, `+
+			`each with "text" (the exact piece of the command), "kind" (one of "command", "flag", "argument", "pipe", "redirection", "operator", "substitution", "other") and "explanation"), `+
+			`"side_effects" (array of strings describing anything destructive, irreversible, privileged or network-related; empty if none), `+
+			`"risk_level" (one of "safe", "low", "medium", "high", "critical").`,
+		shellType, osName,
+	) + shellGuidance(shellType)
+}
+
+// ExplainCommand asks the model to break down an existing command for the
+// given target OS. targetOS accepts the same values as GenerateCommand.
+func (c *Client) ExplainCommand(ctx context.Context, command, targetOS string) (*Explanation, error) {
+	if err := c.cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, fmt.Errorf("no command to explain")
+	}
+
+	osName, shellType := c.resolveTarget(targetOS)
+	content, err := c.completeJSON(ctx, completionRequest{
+		Messages: []Message{
+			{Role: "system", Content: explainSystemPrompt(osName, shellType)},
+			{Role: "user", Content: fmt.Sprintf("Explain this command:\n%s", command)},
+		},
+		Schema: explanationSchema,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseExplanation(content)
+}
+
+// parseExplanation decodes a model reply into an Explanation. A reply
+// without any JSON object is kept as a plain summary; an object without a
+// summary is an error.
+func parseExplanation(content string) (*Explanation, error) {
+	var exp Explanation
+
+	raw, ok := extractJSONObject(content)
+	if !ok || json.Unmarshal([]byte(raw), &exp) != nil {
+		summary := unfence(stripReasoning(content))
+		if summary == "" {
+			return nil, fmt.Errorf("API returned an empty response")
+		}
+		return &Explanation{
+			Summary:     summary,
+			Segments:    []Segment{},
+			SideEffects: []string{},
+			RiskLevel:   RiskUnknown,
+		}, nil
+	}
+
+	exp.Summary = strings.TrimSpace(exp.Summary)
+	if exp.Summary == "" {
+		return nil, fmt.Errorf("API returned no explanation")
+	}
+
+	exp.RiskLevel = normalizeRisk(exp.RiskLevel)
+	if exp.Segments == nil {
+		exp.Segments = []Segment{}
+	}
+	if exp.SideEffects == nil {
+		exp.SideEffects = []string{}
+	}
+	return &exp, nil
+}
@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestExplainCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if !strings.Contains(req.Messages[0].Content, "PowerShell on Windows") {
+			t.Errorf("expected target OS in system prompt, got %q", req.Messages[0].Content)
+		}
+		if !strings.Contains(req.Messages[1].Content, "Get-ChildItem -Recurse | Remove-Item") {
+			t.Errorf("expected command in user message, got %q", req.Messages[1].Content)
+		}
+
+		content := `{"summary": "Deletes every file below the current directory.",
+			"segments": [
+				{"text": "Get-ChildItem -Recurse", "kind": "command", "explanation": "Lists items recursively"},
+				{"text": "|", "kind": "pipe", "explanation": "Passes items on"},
+				{"text": "Remove-Item", "kind": "command", "explanation": "Deletes each item"}
+			],
+			"side_effects": ["Permanently deletes files"],
+			"risk_level": "HIGH"}`
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	exp, err := client.ExplainCommand(testContext(t), "Get-ChildItem -Recurse | Remove-Item", "win")
+	if err != nil {
+		t.Fatalf("ExplainCommand failed: %v", err)
+	}
+	if len(exp.Segments) != 3 || exp.Segments[1].Kind != "pipe" {
+		t.Errorf("unexpected segments: %+v", exp.Segments)
+	}
+	if exp.RiskLevel != RiskHigh || len(exp.SideEffects) != 1 {
+		t.Errorf("unexpected risk assessment: %+v", exp)
+	}
+}
+
+func TestExplainCommand_Empty(t *testing.T) {
+	client := NewClient(&config.Config{APIEndpoint: "http://unused", APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	if _, err := client.ExplainCommand(testContext(t), "   ", ""); err == nil {
+		t.Fatal("expected error for empty command")
+	}
+}
+
+func TestParseExplanation_PlainText(t *testing.T) {
+	exp, err := parseExplanation("This lists files.")
+	if err != nil {
+		t.Fatalf("parseExplanation failed: %v", err)
+	}
+	if exp.Summary != "This lists files." || exp.RiskLevel != RiskUnknown || exp.Segments == nil {
+		t.Errorf("unexpected explanation: %+v", exp)
+	}
+}
+
+func TestParseExplanation_NoSummary(t *testing.T) {
+	for _, reply := range []string{`{}`, `{"segments":[]}`, `{"summary": "  ", "risk_level": "low"}`} {
+		if exp, err := parseExplanation(reply); err == nil {
+			t.Errorf("parseExplanation(%q) = %+v; want an error", reply, exp)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFixStderr bounds how much captured stderr is sent; the end of the
+// output usually carries the actual error.
+const maxFixStderr = 4000
+
+// FailedCommand describes a command that did not work.
+type FailedCommand struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+}
+
+// Fix is a corrected command and a one-line reason for the change.
+type Fix struct {
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+}
+
+// fixSchema is the JSON schema for Fix.
+var fixSchema = &jsonSchema{
+	Name: "command_fix",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{"type": "string"},
+			"reason":  map[string]interface{}{"type": "string"},
+		},
+		"required":             []string{"command", "reason"},
+		"additionalProperties": false,
+	},
+}
+
+// fixSystemPrompt builds the system prompt for repairing a command.
+func fixSystemPrompt(osName, shellType string) string {
+	return fmt.Sprintf(
+		"You are a shell command fixer. The user ran a command in %s on %s and it failed. "+
+			"Using the exit code and error output, return a corrected command that achieves what the original intended. "+
+			"The corrected command must differ from the failed one. "+
+			`Respond with ONLY a JSON object, no markdown, with the fields "command" (string) and `+
+			`"reason" (string, one line explaining what was wrong).`,
+		shellType, osName,
+	) + shellGuidance(shellType)
+}
+
+// FixCommand asks the model to repair a failed command for the given target
+// OS. It never returns the failed command itself: if the model insists on
+// it, an error is returned instead.
+func (c *Client) FixCommand(ctx context.Context, failed FailedCommand, targetOS string) (*Fix, error) {
+	if err := c.cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	failed.Command = strings.TrimSpace(failed.Command)
+	if failed.Command == "" {
+		return nil, fmt.Errorf("no command to fix")
+	}
+
+	osName, shellType := c.resolveTarget(targetOS)
+	req := completionRequest{
+		Messages: []Message{
+			{Role: "system", Content: c.withEnvironment(fixSystemPrompt(osName, shellType), targetOS)},
+			{Role: "user", Content: fixUserPrompt(failed)},
+		},
+		Schema: fixSchema,
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		content, err := c.completeJSON(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		fix, err := parseFix(content)
+		if err != nil {
+			return nil, err
+		}
+		if normalizeCommand(fix.Command) != normalizeCommand(failed.Command) {
+			return fix, nil
+		}
+
+		// Point out the repeat once before giving up.
+		req.Messages = append(req.Messages,
+			Message{Role: "assistant", Content: content},
+			Message{Role: "user", Content: "That is the same command that failed. Suggest a different command."},
+		)
+	}
+
+	return nil, fmt.Errorf("the model could only suggest the command that already failed")
+}
+
+// fixUserPrompt describes the failed command, its exit code and its stderr.
+func fixUserPrompt(failed FailedCommand) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Failed command:\n%s\n\nExit code: %d\n", failed.Command, failed.ExitCode)
+
+	stderr := strings.TrimSpace(failed.Stderr)
+	if len(stderr) > maxFixStderr {
+		// Start the kept tail at a rune boundary, not inside a character.
+		cut := len(stderr) - maxFixStderr
+		for cut < len(stderr) && !utf8.RuneStart(stderr[cut]) {
+			cut++
+		}
+		stderr = "..." + stderr[cut:]
+	}
+	if stderr != "" {
+		fmt.Fprintf(&b, "\nError output:\n%s\n", stderr)
+	} else {
+		b.WriteString("\nNo error output was captured.\n")
+	}
+	return b.String()
+}
+
+// parseFix decodes a model reply into a Fix. A reply without any JSON
+// object is treated as a bare command.
+func parseFix(content string) (*Fix, error) {
+	var fix Fix
+
+	raw, ok := extractJSONObject(content)
+	if !ok || json.Unmarshal([]byte(raw), &fix) != nil {
+		fix = Fix{Command: content}
+	}
+
+	fix.Command, _ = sanitizeCommand(fix.Command)
+	fix.Reason = strings.TrimSpace(fix.Reason)
+	if fix.Command == "" {
+		return nil, errNoCommand
+	}
+
+	// Keep the reason to a single line.
+	if i := strings.IndexByte(fix.Reason, '\n'); i >= 0 {
+		fix.Reason = strings.TrimSpace(fix.Reason[:i])
+	}
+	return &fix, nil
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"aiterm/internal/config"
+)
+
+func TestFixCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		user := req.Messages[1].Content
+		for _, want := range []string{"sed -i 's/a/b/' f.txt", "Exit code: 1", "invalid command code"} {
+			if !strings.Contains(user, want) {
+				t.Errorf("expected %q in user message, got %q", want, user)
+			}
+		}
+
+		content := `{"command": "sed -i '' 's/a/b/' f.txt", "reason": "BSD sed requires a backup suffix after -i.\nMore text."}`
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	fix, err := client.FixCommand(testContext(t), FailedCommand{
+		Command:  "sed -i 's/a/b/' f.txt",
+		ExitCode: 1,
+		Stderr:   "sed: 1: \"f.txt\": invalid command code f",
+	}, "mac")
+	if err != nil {
+		t.Fatalf("FixCommand failed: %v", err)
+	}
+	if fix.Command != "sed -i '' 's/a/b/' f.txt" {
+		t.Errorf("unexpected command: %q", fix.Command)
+	}
+	if fix.Reason != "BSD sed requires a backup suffix after -i." {
+		t.Errorf("expected a one-line reason, got %q", fix.Reason)
+	}
+}
+
+func TestFixCommand_RefusesIdenticalCommand(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		content := `{"command": "ls  /nope", "reason": "Looks fine."}`
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	_, err := client.FixCommand(testContext(t), FailedCommand{Command: "ls /nope", ExitCode: 2}, "")
+	if err == nil {
+		t.Fatal("expected error when the model repeats the failed command")
+	}
+	if calls != 2 {
+		t.Errorf("expected one retry after the repeat, got %d requests", calls)
+	}
+}
+
+func TestFixUserPrompt_TruncatesStderr(t *testing.T) {
+	prompt := fixUserPrompt(FailedCommand{
+		Command:  "make",
+		ExitCode: 2,
+		Stderr:   strings.Repeat("x", maxFixStderr) + "the real error",
+	})
+	if !strings.Contains(prompt, "the real error") {
+		t.Error("expected the end of stderr to be kept")
+	}
+	if len(prompt) > maxFixStderr+200 {
+		t.Errorf("prompt not truncated: %d bytes", len(prompt))
+	}
+
+	// The cut must not split a multi-byte character.
+	for offset := 0; offset < 3; offset++ {
+		prompt = fixUserPrompt(FailedCommand{Command: "make", Stderr: strings.Repeat("ü", maxFixStderr) + strings.Repeat("x", offset)})
+		if !utf8.ValidString(prompt) {
+			t.Errorf("offset %d: prompt is not valid UTF-8", offset)
+		}
+	}
+}
+
+func TestParseFix_NoCommand(t *testing.T) {
+	if _, err := parseFix(`{"command": "", "reason": "nothing to do"}`); !errors.Is(err, errNoCommand) {
+		t.Errorf("expected errNoCommand, got %v", err)
+	}
+}
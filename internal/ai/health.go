@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+// healthFileName is the file under the config directory that remembers
+// endpoints which recently failed, so later invocations skip them.
+const healthFileName = "health.json"
+
+// healthStore records until when each failed endpoint should be skipped.
+type healthStore struct {
+	path      string
+	Unhealthy map[string]time.Time `json:"unhealthy"`
+}
+
+// loadHealth reads the health file. A missing or unreadable file yields an
+// empty store; endpoint health is only a hint.
+func loadHealth() *healthStore {
+	h := &healthStore{Unhealthy: map[string]time.Time{}}
+
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return h
+	}
+	h.path = filepath.Join(dir, healthFileName)
+
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		return h
+	}
+	if err := json.Unmarshal(data, h); err != nil || h.Unhealthy == nil {
+		h.Unhealthy = map[string]time.Time{}
+	}
+	return h
+}
+
+// save writes the store back to disk, replacing the file atomically so
+// concurrent invocations never read a partial file.
+func (h *healthStore) save() {
+	if h.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), healthFileName+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), h.path)
+}
+
+// order returns the chain with endpoints that are cooling down moved to the
+// end, so they are still tried as a last resort.
+func (h *healthStore) order(chain []config.Endpoint, now time.Time) []config.Endpoint {
+	var healthy, cooling []config.Endpoint
+	for _, ep := range chain {
+		if until, ok := h.Unhealthy[healthKey(ep)]; ok && now.Before(until) {
+			cooling = append(cooling, ep)
+			continue
+		}
+		healthy = append(healthy, ep)
+	}
+	return append(healthy, cooling...)
+}
+
+// markFailed skips ep until the given time.
+func (h *healthStore) markFailed(ep config.Endpoint, until time.Time) {
+	h.Unhealthy[healthKey(ep)] = until
+}
+
+// markHealthy forgets any earlier failure of ep.
+func (h *healthStore) markHealthy(ep config.Endpoint) {
+	delete(h.Unhealthy, healthKey(ep))
+}
+
+// healthKey identifies an endpoint without including its token.
+func healthKey(ep config.Endpoint) string {
+	return ep.APIEndpoint + " " + ep.Model
+}
+
+// shouldFailover reports whether err means the next endpoint should be
+// tried: the endpoint was unreachable, broken, or rejected the credentials.
+func shouldFailover(err error) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch {
+	case apiErr.status == 0, apiErr.status >= 500:
+		return true
+	case apiErr.status == http.StatusUnauthorized, apiErr.status == http.StatusForbidden:
+		return true
+	}
+	return false
+}
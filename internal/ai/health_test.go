@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+func TestGenerateCommand_FailsOverToNextEndpoint(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var primaryCalls int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryCalls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer backup-token" {
+			t.Error("fallback endpoint must use its own token")
+		}
+		resp := map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "ls -la"}},
+			},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer secondary.Close()
+
+	cfg := &config.Config{
+		APIEndpoint: primary.URL,
+		APIToken:    "test-token",
+		Model:       "gpt-4o-mini",
+		Endpoints: []config.Endpoint{
+			{APIEndpoint: secondary.URL, APIToken: "backup-token", Model: "backup-model"},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		client := NewClient(cfg)
+		cmd, err := client.GenerateCommand(testContext(t), "list all files", "")
+		if err != nil {
+			t.Fatalf("GenerateCommand failed: %v", err)
+		}
+		if cmd != "ls -la" {
+			t.Errorf("unexpected command: %q, want %q", cmd, "ls -la")
+		}
+
+		ep, ok := client.AnsweredBy()
+		if !ok || ep.APIEndpoint != secondary.URL {
+			t.Errorf("expected answer from fallback endpoint, got %+v", ep)
+		}
+	}
+
+	// The second invocation should skip the primary while it cools down.
+	if primaryCalls != 1 {
+		t.Errorf("expected primary to be tried once, got %d", primaryCalls)
+	}
+}
+
+func TestGenerateCommand_NoFailoverOnClientError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"message": "unknown model"}}`))
+	}))
+	defer primary.Close()
+
+	var secondaryCalls int32
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&secondaryCalls, 1)
+	}))
+	defer secondary.Close()
+
+	client := NewClient(&config.Config{
+		APIEndpoint: primary.URL,
+		APIToken:    "test-token",
+		Model:       "gpt-4o-mini",
+		Endpoints: []config.Endpoint{
+			{APIEndpoint: secondary.URL, APIToken: "backup-token", Model: "backup-model"},
+		},
+	})
+
+	if _, err := client.GenerateCommand(testContext(t), "list files", ""); err == nil {
+		t.Fatal("expected error for bad request")
+	}
+	if secondaryCalls != 0 {
+		t.Errorf("a 400 must not fail over, but fallback was called %d times", secondaryCalls)
+	}
+}
+
+func TestHealthStoreOrder(t *testing.T) {
+	now := time.Now()
+	a := config.Endpoint{APIEndpoint: "https://a", Model: "m"}
+	b := config.Endpoint{APIEndpoint: "https://b", Model: "m"}
+	c := config.Endpoint{APIEndpoint: "https://c", Model: "m"}
+
+	h := &healthStore{Unhealthy: map[string]time.Time{}}
+	h.markFailed(a, now.Add(time.Minute))
+	h.markFailed(b, now.Add(-time.Minute)) // cooldown already expired
+
+	order := h.order([]config.Endpoint{a, b, c}, now)
+	if len(order) != 3 || order[0] != b || order[1] != c || order[2] != a {
+		t.Errorf("unexpected order: %+v", order)
+	}
+
+	h.markHealthy(a)
+	if order := h.order([]config.Endpoint{a, b, c}, now); order[0] != a {
+		t.Errorf("expected a first after markHealthy, got %+v", order)
+	}
+}
@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Model is a model offered by an endpoint. Owner and ContextLength are
+// empty when the listing doesn't report them.
+type Model struct {
+	ID            string `json:"id"`
+	Owner         string `json:"owner,omitempty"`
+	ContextLength int    `json:"context_length,omitempty"`
+
+	// Aliases are other names the endpoint resolves to this model, such
+	// as "llama3" for Ollama's "llama3:latest".
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// modelLister is implemented by providers whose API lists the models
+// available to the token.
+type modelLister interface {
+	// newModelsRequest builds the request for the model listing.
+	newModelsRequest(ctx context.Context) (*http.Request, error)
+
+	// parseModels extracts the models from the listing's response body.
+	parseModels(body []byte) ([]Model, error)
+}
+
+// ListModels returns the models offered by the primary endpoint, sorted by
+// ID. The listing URL is derived from api_endpoint: /v1/models for
+// OpenAI-compatible endpoints and Anthropic, /api/tags for Ollama and
+// /models for Gemini.
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	if c.transportErr != nil {
+		return nil, c.transportErr
+	}
+	ep := c.cfg.EndpointChain()[0]
+	lister, ok := newProvider(ep).(modelLister)
+	if !ok {
+		return nil, fmt.Errorf("provider %s cannot list models", ep.Provider)
+	}
+	req, err := lister.newModelsRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, &ctxError{err: ctx.Err()}
+		}
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model list: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("authentication failed — check your API token")
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("the endpoint has no model list at %s (HTTP 404)", req.URL.Redacted())
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("API returned HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	models, err := lister.parseModels(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// HasModel reports whether id names one of models, by ID or alias.
+func HasModel(models []Model, id string) bool {
+	for _, m := range models {
+		if m.ID == id {
+			return true
+		}
+		for _, alias := range m.Aliases {
+			if alias == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SimilarModels returns the IDs in models that id was likely meant to be:
+// those differing only in case, containing it, or within a few typos.
+func SimilarModels(models []Model, id string) []string {
+	want := strings.ToLower(id)
+	var similar []string
+	for _, m := range models {
+		have := strings.ToLower(m.ID)
+		if have == want || strings.Contains(have, want) || strings.Contains(want, have) || editDistance(have, want) <= 2 {
+			similar = append(similar, m.ID)
+		}
+	}
+	return similar
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// listingURL derives the URL of a model listing from a chat endpoint by
+// replacing the first of suffixes found at the end of its path with
+// listing. Without a known suffix, the endpoint is taken as the API base.
+// Query parameters such as an API version are kept.
+func listingURL(endpoint string, suffixes []string, listing string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid API endpoint: %w", err)
+	}
+	path := strings.TrimRight(u.Path, "/")
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(path, suffix) {
+			path = strings.TrimSuffix(path, suffix)
+			break
+		}
+	}
+	u.Path = path + listing
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// newGetRequest creates a GET request for url.
+func newGetRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return req, nil
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestListModels(t *testing.T) {
+	tests := []struct {
+		provider string
+		endpoint string
+		path     string
+		header   string
+		body     string
+		want     []Model
+	}{
+		{
+			provider: config.ProviderOpenAI,
+			endpoint: "/v1/chat/completions",
+			path:     "/v1/models",
+			header:   "Authorization",
+			body:     `{"data": [{"id": "gpt-4o-mini", "owned_by": "openai"}, {"id": "llama-3", "owned_by": "vllm", "max_model_len": 8192}]}`,
+			want:     []Model{{ID: "gpt-4o-mini", Owner: "openai"}, {ID: "llama-3", Owner: "vllm", ContextLength: 8192}},
+		},
+		{
+			provider: config.ProviderAnthropic,
+			endpoint: "/v1/messages",
+			path:     "/v1/models",
+			header:   "x-api-key",
+			body:     `{"data": [{"id": "claude-sonnet-4-5", "display_name": "Claude Sonnet 4.5"}], "has_more": false}`,
+			want:     []Model{{ID: "claude-sonnet-4-5", Owner: "anthropic"}},
+		},
+		{
+			provider: config.ProviderOllama,
+			endpoint: "/api/chat",
+			path:     "/api/tags",
+			body:     `{"models": [{"name": "qwen2.5-coder:7b"}, {"name": "llama3.2:latest"}]}`,
+			want:     []Model{{ID: "llama3.2:latest", Aliases: []string{"llama3.2"}}, {ID: "qwen2.5-coder:7b"}},
+		},
+		{
+			provider: config.ProviderGemini,
+			endpoint: "/v1beta/models/gemini-2.0-flash:generateContent",
+			path:     "/v1beta/models",
+			header:   "x-goog-api-key",
+			body: `{"models": [
+				{"name": "models/gemini-2.0-flash", "inputTokenLimit": 1048576, "supportedGenerationMethods": ["generateContent"]},
+				{"name": "models/text-embedding-004", "supportedGenerationMethods": ["embedContent"]}]}`,
+			want: []Model{{ID: "gemini-2.0-flash", Owner: "google", ContextLength: 1048576}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != tt.path {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if tt.header != "" && !strings.Contains(r.Header.Get(tt.header), "test-token") {
+					t.Errorf("missing %s header", tt.header)
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			models, err := newTestClient(server.URL+tt.endpoint, tt.provider).ListModels(testContext(t))
+			if err != nil {
+				t.Fatalf("ListModels failed: %v", err)
+			}
+			if !reflect.DeepEqual(models, tt.want) {
+				t.Errorf("got %+v, want %+v", models, tt.want)
+			}
+		})
+	}
+}
+
+func TestListModels_Errors(t *testing.T) {
+	for status, want := range map[int]string{
+		http.StatusUnauthorized: "authentication failed",
+		http.StatusNotFound:     "no model list",
+		http.StatusBadGateway:   "HTTP 502",
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		_, err := newTestClient(server.URL+"/v1/chat/completions", config.ProviderOpenAI).ListModels(testContext(t))
+		server.Close()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("HTTP %d: expected %q, got %v", status, want, err)
+		}
+	}
+}
+
+func TestListingURL(t *testing.T) {
+	suffixes := []string{"/chat/completions", "/completions"}
+	tests := map[string]string{
+		"https://api.openai.com/v1/chat/completions":                             "https://api.openai.com/v1/models",
+		"http://localhost:4000/v1/":                                              "http://localhost:4000/v1/models",
+		"https://example.openai.azure.com/openai/chat/completions?api-version=1": "https://example.openai.azure.com/openai/models?api-version=1",
+	}
+	for endpoint, want := range tests {
+		got, err := listingURL(endpoint, suffixes, "/models")
+		if err != nil || got != want {
+			t.Errorf("listingURL(%q) = %q, %v; want %q", endpoint, got, err, want)
+		}
+	}
+}
+
+func TestSimilarModels(t *testing.T) {
+	models := []Model{{ID: "gpt-4o"}, {ID: "gpt-4o-mini"}, {ID: "claude-haiku"}}
+	if got := SimilarModels(models, "gpt4o-mini"); !reflect.DeepEqual(got, []string{"gpt-4o-mini"}) {
+		t.Errorf("unexpected suggestions %v", got)
+	}
+	if got := SimilarModels(models, "llama"); len(got) != 0 {
+		t.Errorf("expected no suggestions, got %v", got)
+	}
+	if !HasModel(models, "gpt-4o") || HasModel(models, "GPT-4o") {
+		t.Error("HasModel must match IDs exactly")
+	}
+
+	tagged := []Model{{ID: "llama3:latest", Aliases: []string{"llama3"}}, {ID: "qwen2.5-coder:7b"}}
+	if !HasModel(tagged, "llama3") || !HasModel(tagged, "llama3:latest") || HasModel(tagged, "qwen2.5-coder") {
+		t.Error("HasModel must accept aliases, and only those")
+	}
+}
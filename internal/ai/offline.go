@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aiterm/internal/offline"
+)
+
+// OfflineAnswer describes commands that came from the built-in offline
+// library instead of a model.
+type OfflineAnswer struct {
+	// Task is the library task that matched best.
+	Task string
+	// Cause is the failure that triggered the fallback, or nil when the
+	// library was asked explicitly.
+	Cause error
+}
+
+// Offline reports whether the most recent generation was answered from
+// the offline library.
+func (c *Client) Offline() (OfflineAnswer, bool) {
+	if c.offline == nil {
+		return OfflineAnswer{}, false
+	}
+	return *c.offline, true
+}
+
+// SuggestOffline answers description from the offline library without
+// contacting any endpoint, with up to n commands. It fails when no task in
+// the library matches.
+func (c *Client) SuggestOffline(description, targetOS string, n int) ([]string, error) {
+	return c.suggestOffline(description, targetOS, n, nil)
+}
+
+// suggestOffline looks description up in the offline library and records
+// the answer, with cause as the reason for not asking the model.
+func (c *Client) suggestOffline(description, targetOS string, n int, cause error) ([]string, error) {
+	osName, shellType := c.resolveTarget(targetOS)
+	suggestions := offline.Suggest(description, osName, shellType, n)
+	if len(suggestions) == 0 {
+		return nil, fmt.Errorf("no offline suggestion for %q on %s / %s", description, osName, shellType)
+	}
+
+	commands := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		commands[i] = s.Command
+	}
+
+	c.offline = &OfflineAnswer{Task: suggestions[0].Task, Cause: cause}
+	c.remember(description, targetOS, []Message{
+		{Role: "system", Content: systemPrompt(osName, shellType)},
+		{Role: "user", Content: commandInstruction(description)},
+	}, commands[0])
+	return commands, nil
+}
+
+// fallBackOffline answers from the offline library when err means that no
+// model could be reached and the fallback is enabled. Otherwise, or when
+// nothing in the library matches, it returns err.
+func (c *Client) fallBackOffline(ctx context.Context, err error, description, targetOS string, n int) ([]string, error) {
+	if !c.cfg.OfflineFallback || !isUnreachable(ctx, err) {
+		return nil, err
+	}
+	commands, offlineErr := c.suggestOffline(description, targetOS, n, err)
+	if offlineErr != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// isUnreachable reports whether err means that no model could be reached:
+// the connection failed, the request timed out or a gateway reported the
+// backend as down, as while a hosted model is cold-starting. An interrupted
+// request is not a failure to reach the model.
+func isUnreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return errors.Is(ctx.Err(), context.DeadlineExceeded)
+	}
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.status {
+	case 0, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestGenerateCommand_OfflineFallback(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	newClient := func() *Client {
+		client := newTestClient(server.URL, config.ProviderOpenAI)
+		client.cfg.Shell = "bash"
+		client.cfg.OfflineFallback = true
+		return client
+	}
+
+	// A cold-starting backend falls back to the library.
+	client := newClient()
+	cmd, err := client.GenerateCommand(testContext(t), "kill the process on port 3000", "linux")
+	if err != nil || cmd != "fuser -k 3000/tcp" {
+		t.Fatalf("GenerateCommand = %q, %v; want the offline suggestion", cmd, err)
+	}
+	answer, ok := client.Offline()
+	if !ok || answer.Task != "Kill the process listening on port 3000" || answer.Cause == nil {
+		t.Errorf("unexpected offline answer: %+v, %v", answer, ok)
+	}
+	if ex, ok := client.LastExchange(); !ok || ex.Command != cmd {
+		t.Error("expected the offline answer to be remembered for refine")
+	}
+
+	// So does a refused connection, also when streaming.
+	client = newTestClient("http://127.0.0.1:1", config.ProviderOpenAI)
+	client.cfg.OfflineFallback = true
+	if cmd, err := client.GenerateCommandStream(testContext(t), "show free disk space", "linux", nil); err != nil || cmd != "df -h" {
+		t.Errorf("GenerateCommandStream = %q, %v; want df -h", cmd, err)
+	}
+
+	// Nothing matching, a disabled fallback and other errors keep the error.
+	if _, err := newClient().GenerateCommand(testContext(t), "write a haiku", "linux"); err == nil {
+		t.Error("expected an error when no task matches")
+	}
+	client = newClient()
+	client.cfg.OfflineFallback = false
+	if _, err := client.GenerateCommand(testContext(t), "show free disk space", "linux"); err == nil {
+		t.Error("expected an error with the fallback disabled")
+	}
+	status = http.StatusUnauthorized
+	client = newClient()
+	if _, err := client.GenerateCommand(testContext(t), "show free disk space", "linux"); err == nil {
+		t.Error("expected authentication failures not to fall back")
+	}
+	if _, ok := client.Offline(); ok {
+		t.Error("expected no offline answer")
+	}
+}
+
+func TestSuggestOffline(t *testing.T) {
+	client := newTestClient("http://unused", config.ProviderOpenAI)
+	client.cfg.Shell = "pwsh"
+
+	commands, err := client.SuggestOffline("find files larger than 1 MB", "win", 2)
+	if err != nil || len(commands) == 0 || commands[0] != "Get-ChildItem -Recurse -File | Where-Object { $_.Length -gt 1MB }" {
+		t.Fatalf("SuggestOffline = %q, %v", commands, err)
+	}
+	if answer, ok := client.Offline(); !ok || answer.Cause != nil {
+		t.Errorf("expected an explicit offline answer, got %+v", answer)
+	}
+
+	if _, err := client.SuggestOffline("write a haiku", "win", 1); err == nil {
+		t.Error("expected an error when no task matches")
+	}
+}
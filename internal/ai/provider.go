@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aiterm/internal/config"
+)
+
+// Message represents a single message in the chat history.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// completionRequest is the provider-neutral form of a chat exchange.
+type completionRequest struct {
+	Messages []Message
+	Stream   bool
+
+	// N asks for several independent choices in one response. Providers
+	// that cannot do this ignore it and return a single choice.
+	N int
+
+	// Schema constrains the reply to a JSON object. Providers without
+	// structured output support ignore it and rely on the prompt.
+	Schema *jsonSchema
+
+	// NoStreamUsage leaves out the request for token usage at the end of
+	// a stream, for compatible backends that reject the parameter.
+	NoStreamUsage bool
+}
+
+// provider adapts a completionRequest to a specific backend API and parses
+// that backend's responses.
+type provider interface {
+	// newRequest builds the HTTP request for the given exchange.
+	newRequest(ctx context.Context, req completionRequest) (*http.Request, error)
+
+	// parseResponse extracts the reply text from a complete response body.
+	parseResponse(body []byte) (string, error)
+
+	// parseStreamLine extracts the content delta carried by one line of a
+	// streamed response. done reports that the stream has finished.
+	parseStreamLine(line string) (delta string, done bool, err error)
+}
+
+// multiChoiceProvider is implemented by providers whose responses can carry
+// several choices (see completionRequest.N).
+type multiChoiceProvider interface {
+	parseChoices(body []byte) ([]string, error)
+}
+
+// newProvider returns the adapter selected by ep.Provider. Unknown values
+// fall back to the OpenAI adapter; Config.Validate reports them.
+func newProvider(ep config.Endpoint) provider {
+	switch strings.ToLower(ep.Provider) {
+	case config.ProviderAnthropic:
+		return &anthropicProvider{ep: ep}
+	case config.ProviderOllama:
+		return &ollamaProvider{ep: ep}
+	case config.ProviderGemini:
+		return &geminiProvider{ep: ep}
+	default:
+		return &openAIProvider{ep: ep}
+	}
+}
+
+// newJSONRequest creates a POST request with body encoded as JSON.
+func newJSONRequest(ctx context.Context, url string, body interface{}) (*http.Request, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// sseData returns the payload of a server-sent event "data:" line. ok is
+// false for blank separators, comments and other event fields.
+func sseData(line string) (data string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "data:") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "data:")), true
+}
+
+// splitSystem separates system messages from the rest of the conversation
+// for APIs that take the system prompt as a dedicated field.
+func splitSystem(messages []Message) (system string, rest []Message) {
+	var parts []string
+	for _, m := range messages {
+		if m.Role == "system" {
+			parts = append(parts, m.Content)
+			continue
+		}
+		rest = append(rest, m)
+	}
+	return strings.Join(parts, "\n\n"), rest
+}
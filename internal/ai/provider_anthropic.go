@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aiterm/internal/config"
+)
+
+// anthropicVersion is the Messages API version sent with every request.
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens caps the reply length; the Messages API requires it.
+const anthropicMaxTokens = 1024
+
+// anthropicProvider talks to the native Anthropic Messages API.
+type anthropicProvider struct {
+	ep config.Endpoint
+}
+
+// anthropicRequest represents the request body for the Messages API.
+type anthropicRequest struct {
+	Model     string    `json:"model"`
+	System    string    `json:"system,omitempty"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens"`
+	Stream    bool      `json:"stream,omitempty"`
+}
+
+// anthropicResponse represents the response body from the Messages API.
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// anthropicEvent represents a single server-sent event of a streamed message.
+type anthropicEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (p *anthropicProvider) newRequest(ctx context.Context, req completionRequest) (*http.Request, error) {
+	system, messages := splitSystem(req.Messages)
+
+	httpReq, err := newJSONRequest(ctx, p.ep.APIEndpoint, anthropicRequest{
+		Model:     p.ep.Model,
+		System:    system,
+		Messages:  messages,
+		MaxTokens: anthropicMaxTokens,
+		Stream:    req.Stream,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("x-api-key", p.ep.APIToken)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	return httpReq, nil
+}
+
+func (p *anthropicProvider) parseResponse(body []byte) (string, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	if resp.Error != nil {
+		return "", fmt.Errorf("API error: %s", resp.Error.Message)
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("API returned no text content")
+	}
+
+	return text.String(), nil
+}
+
+// anthropicUsage holds the token counts of a response. Streams report the
+// input tokens in message_start and the output tokens in message_delta.
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (p *anthropicProvider) parseUsage(payload []byte) (Usage, bool) {
+	var resp struct {
+		Usage   *anthropicUsage `json:"usage"`
+		Message struct {
+			Usage *anthropicUsage `json:"usage"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return Usage{}, false
+	}
+
+	usage := resp.Usage
+	if usage == nil {
+		usage = resp.Message.Usage
+	}
+	if usage == nil {
+		return Usage{}, false
+	}
+	return Usage{PromptTokens: usage.InputTokens, CompletionTokens: usage.OutputTokens}, true
+}
+
+func (p *anthropicProvider) parseStreamLine(line string) (string, bool, error) {
+	data, ok := sseData(line)
+	if !ok {
+		return "", false, nil
+	}
+
+	var event anthropicEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return "", false, fmt.Errorf("failed to parse stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "content_block_delta":
+		if event.Delta.Type == "text_delta" {
+			return event.Delta.Text, false, nil
+		}
+	case "message_stop":
+		return "", true, nil
+	case "error":
+		if event.Error != nil {
+			return "", false, fmt.Errorf("API error: %s", event.Error.Message)
+		}
+		return "", false, fmt.Errorf("API error")
+	}
+	return "", false, nil
+}
+
+// anthropicModels is the response body of /v1/models.
+type anthropicModels struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+func (p *anthropicProvider) newModelsRequest(ctx context.Context) (*http.Request, error) {
+	url, err := listingURL(p.ep.APIEndpoint, []string{"/messages"}, "/models")
+	if err != nil {
+		return nil, err
+	}
+	req, err := newGetRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	// One page of up to 1000 models covers the whole listing.
+	q := req.URL.Query()
+	q.Set("limit", "1000")
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("x-api-key", p.ep.APIToken)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return req, nil
+}
+
+func (p *anthropicProvider) parseModels(body []byte) ([]Model, error) {
+	var resp anthropicModels
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]Model, len(resp.Data))
+	for i, m := range resp.Data {
+		models[i] = Model{ID: m.ID, Owner: "anthropic"}
+	}
+	return models, nil
+}
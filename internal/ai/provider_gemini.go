@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aiterm/internal/config"
+)
+
+// geminiProvider talks to the Google Gemini generateContent API.
+type geminiProvider struct {
+	ep config.Endpoint
+}
+
+// geminiContent represents a single turn of a Gemini conversation.
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart represents a text part of a Gemini content turn.
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiRequest represents the request body for generateContent.
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// geminiGenerationConfig carries output constraints for generateContent.
+type geminiGenerationConfig struct {
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
+}
+
+// geminiResponse represents a generateContent response and, when
+// streaming, each server-sent event.
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+// url returns the generateContent (or streamGenerateContent) URL. The
+// configured endpoint is either a full method URL or the API base, such as
+// https://generativelanguage.googleapis.com/v1beta.
+func (p *geminiProvider) url(stream bool) string {
+	endpoint := strings.TrimRight(p.ep.APIEndpoint, "/")
+	if !strings.Contains(endpoint, ":generateContent") && !strings.Contains(endpoint, ":streamGenerateContent") {
+		endpoint = fmt.Sprintf("%s/models/%s:generateContent", endpoint, p.ep.Model)
+	}
+
+	endpoint = strings.Replace(endpoint, ":streamGenerateContent", ":generateContent", 1)
+	if stream {
+		endpoint = strings.Replace(endpoint, ":generateContent", ":streamGenerateContent", 1)
+		if strings.Contains(endpoint, "?") {
+			endpoint += "&alt=sse"
+		} else {
+			endpoint += "?alt=sse"
+		}
+	}
+	return endpoint
+}
+
+func (p *geminiProvider) newRequest(ctx context.Context, req completionRequest) (*http.Request, error) {
+	system, messages := splitSystem(req.Messages)
+
+	body := geminiRequest{}
+	if system != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+	for _, m := range messages {
+		role := "user"
+		if m.Role == "assistant" {
+			role = "model"
+		}
+		body.Contents = append(body.Contents, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+
+	// Gemini's responseSchema dialect differs from JSON schema; asking for
+	// JSON output is enough together with the prompt.
+	if req.Schema != nil {
+		body.GenerationConfig = &geminiGenerationConfig{ResponseMimeType: "application/json"}
+	}
+
+	httpReq, err := newJSONRequest(ctx, p.url(req.Stream), body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("x-goog-api-key", p.ep.APIToken)
+	return httpReq, nil
+}
+
+func (p *geminiProvider) parseResponse(body []byte) (string, error) {
+	var resp geminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	if resp.Error != nil {
+		return "", fmt.Errorf("API error: %s", resp.Error.Message)
+	}
+
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("API returned no candidates")
+	}
+
+	return geminiText(resp.Candidates[0].Content), nil
+}
+
+func (p *geminiProvider) parseStreamLine(line string) (string, bool, error) {
+	data, ok := sseData(line)
+	if !ok {
+		return "", false, nil
+	}
+
+	var resp geminiResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return "", false, fmt.Errorf("failed to parse stream event: %w", err)
+	}
+	if resp.Error != nil {
+		return "", false, fmt.Errorf("API error: %s", resp.Error.Message)
+	}
+	if len(resp.Candidates) == 0 {
+		return "", false, nil
+	}
+
+	// Gemini has no end-of-stream marker; the stream simply ends.
+	return geminiText(resp.Candidates[0].Content), false, nil
+}
+
+func (p *geminiProvider) parseUsage(payload []byte) (Usage, bool) {
+	var resp geminiResponse
+	if err := json.Unmarshal(payload, &resp); err != nil || resp.UsageMetadata == nil {
+		return Usage{}, false
+	}
+	return Usage{
+		PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+		CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+	}, true
+}
+
+// geminiText joins the text parts of a content turn.
+func geminiText(content geminiContent) string {
+	var text strings.Builder
+	for _, part := range content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// geminiModels is the response body of the models listing.
+type geminiModels struct {
+	Models []struct {
+		Name                       string   `json:"name"`
+		InputTokenLimit            int      `json:"inputTokenLimit"`
+		SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	} `json:"models"`
+}
+
+func (p *geminiProvider) newModelsRequest(ctx context.Context) (*http.Request, error) {
+	// A full method URL names the model: cut it back to the API base.
+	endpoint := p.ep.APIEndpoint
+	if i := strings.Index(endpoint, "/models/"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	url, err := listingURL(endpoint, []string{"/models"}, "/models")
+	if err != nil {
+		return nil, err
+	}
+	req, err := newGetRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Set("pageSize", "1000")
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("x-goog-api-key", p.ep.APIToken)
+	return req, nil
+}
+
+func (p *geminiProvider) parseModels(body []byte) ([]Model, error) {
+	var resp geminiModels
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	var models []Model
+	for _, m := range resp.Models {
+		// Skip embedding and other models that cannot chat.
+		for _, method := range m.SupportedGenerationMethods {
+			if method == "generateContent" {
+				models = append(models, Model{
+					ID:            strings.TrimPrefix(m.Name, "models/"),
+					Owner:         "google",
+					ContextLength: m.InputTokenLimit,
+				})
+				break
+			}
+		}
+	}
+	return models, nil
+}
@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aiterm/internal/config"
+)
+
+// ollamaProvider talks to Ollama's native /api/chat endpoint.
+type ollamaProvider struct {
+	ep config.Endpoint
+}
+
+// ollamaRequest represents the request body for /api/chat. Stream is always
+// sent because Ollama streams unless told otherwise.
+type ollamaRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+
+	// Format takes a JSON schema to constrain the reply.
+	Format map[string]interface{} `json:"format,omitempty"`
+}
+
+// ollamaResponse represents a complete /api/chat response and, when
+// streaming, each newline-delimited JSON chunk.
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+
+	// Token counts, sent with the final (done) response only.
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+func (p *ollamaProvider) newRequest(ctx context.Context, req completionRequest) (*http.Request, error) {
+	body := ollamaRequest{
+		Model:    p.ep.Model,
+		Messages: req.Messages,
+		Stream:   req.Stream,
+	}
+	if req.Schema != nil {
+		body.Format = req.Schema.Schema
+	}
+
+	httpReq, err := newJSONRequest(ctx, p.ep.APIEndpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plain Ollama needs no token, but reverse proxies in front of it might.
+	if p.ep.APIToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.ep.APIToken)
+	}
+	return httpReq, nil
+}
+
+func (p *ollamaProvider) parseResponse(body []byte) (string, error) {
+	var resp ollamaResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	if resp.Error != "" {
+		return "", fmt.Errorf("API error: %s", resp.Error)
+	}
+
+	return resp.Message.Content, nil
+}
+
+func (p *ollamaProvider) parseStreamLine(line string) (string, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", false, nil
+	}
+
+	var chunk ollamaResponse
+	if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+		return "", false, fmt.Errorf("failed to parse stream chunk: %w", err)
+	}
+	if chunk.Error != "" {
+		return "", false, fmt.Errorf("API error: %s", chunk.Error)
+	}
+
+	return chunk.Message.Content, chunk.Done, nil
+}
+
+func (p *ollamaProvider) parseUsage(payload []byte) (Usage, bool) {
+	var resp ollamaResponse
+	if err := json.Unmarshal(payload, &resp); err != nil || !resp.Done {
+		return Usage{}, false
+	}
+	return Usage{PromptTokens: resp.PromptEvalCount, CompletionTokens: resp.EvalCount}, true
+}
+
+// ollamaModels is the response body of /api/tags, the locally installed
+// models.
+type ollamaModels struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+func (p *ollamaProvider) newModelsRequest(ctx context.Context) (*http.Request, error) {
+	url, err := listingURL(p.ep.APIEndpoint, []string{"/api/chat", "/api/generate"}, "/api/tags")
+	if err != nil {
+		return nil, err
+	}
+	req, err := newGetRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if p.ep.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.ep.APIToken)
+	}
+	return req, nil
+}
+
+func (p *ollamaProvider) parseModels(body []byte) ([]Model, error) {
+	var resp ollamaModels
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]Model, len(resp.Models))
+	for i, m := range resp.Models {
+		models[i] = Model{ID: m.Name}
+		// Ollama resolves a name without a tag to its :latest tag.
+		if name, ok := strings.CutSuffix(m.Name, ":latest"); ok {
+			models[i].Aliases = []string{name}
+		}
+	}
+	return models, nil
+}
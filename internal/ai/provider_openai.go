@@ -0,0 +1,219 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"aiterm/internal/config"
+)
+
+// openAIProvider talks to OpenAI-compatible chat completions endpoints
+// (OpenAI, LiteLLM, Ollama's /v1 API, Azure, ...).
+type openAIProvider struct {
+	ep config.Endpoint
+}
+
+// chatRequest represents the request body for the chat completions API.
+type chatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream,omitempty"`
+	N        int       `json:"n,omitempty"`
+
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+}
+
+// streamOptions asks for a final chunk carrying the token usage, which
+// streamed responses omit by default.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIUsage is the token usage block of a response or final stream chunk.
+type openAIUsage struct {
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// responseFormat requests schema-constrained JSON output.
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string                 `json:"name"`
+		Schema map[string]interface{} `json:"schema"`
+		Strict bool                   `json:"strict"`
+	} `json:"json_schema"`
+}
+
+// chatResponse represents the response body from the chat completions API.
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// streamChunk represents a single server-sent event of a streamed chat completion.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (p *openAIProvider) newRequest(ctx context.Context, req completionRequest) (*http.Request, error) {
+	body := chatRequest{
+		Model:    p.ep.Model,
+		Messages: req.Messages,
+		Stream:   req.Stream,
+		N:        nChoices(req.N),
+	}
+	if req.Stream && !req.NoStreamUsage {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	if req.Schema != nil {
+		body.ResponseFormat = &responseFormat{Type: "json_schema"}
+		body.ResponseFormat.JSONSchema.Name = req.Schema.Name
+		body.ResponseFormat.JSONSchema.Schema = req.Schema.Schema
+		body.ResponseFormat.JSONSchema.Strict = true
+	}
+
+	httpReq, err := newJSONRequest(ctx, p.ep.APIEndpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer "+p.ep.APIToken)
+	return httpReq, nil
+}
+
+func (p *openAIProvider) parseResponse(body []byte) (string, error) {
+	choices, err := p.parseChoices(body)
+	if err != nil {
+		return "", err
+	}
+	return choices[0], nil
+}
+
+func (p *openAIProvider) parseChoices(body []byte) ([]string, error) {
+	var chatResp chatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	// Check for API-level error in response body
+	if chatResp.Error != nil {
+		return nil, fmt.Errorf("API error: %s", chatResp.Error.Message)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("API returned no choices")
+	}
+
+	choices := make([]string, len(chatResp.Choices))
+	for i, choice := range chatResp.Choices {
+		choices[i] = choice.Message.Content
+	}
+	return choices, nil
+}
+
+// nChoices omits n from the request unless more than one choice is wanted,
+// since some compatible backends reject the parameter outright.
+func nChoices(n int) int {
+	if n > 1 {
+		return n
+	}
+	return 0
+}
+
+func (p *openAIProvider) parseStreamLine(line string) (string, bool, error) {
+	data, ok := sseData(line)
+	if !ok {
+		return "", false, nil
+	}
+	if data == "[DONE]" {
+		return "", true, nil
+	}
+
+	var chunk streamChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return "", false, fmt.Errorf("failed to parse stream chunk: %w", err)
+	}
+	if chunk.Error != nil {
+		return "", false, fmt.Errorf("API error: %s", chunk.Error.Message)
+	}
+
+	var delta string
+	for _, choice := range chunk.Choices {
+		delta += choice.Delta.Content
+	}
+	return delta, false, nil
+}
+
+func (p *openAIProvider) parseUsage(payload []byte) (Usage, bool) {
+	var resp openAIUsage
+	if err := json.Unmarshal(payload, &resp); err != nil || resp.Usage == nil {
+		return Usage{}, false
+	}
+	return Usage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens}, true
+}
+
+// openAIModels is the response body of /v1/models. The context length is
+// not part of OpenAI's own listing, but compatible servers report it under
+// various names.
+type openAIModels struct {
+	Data []struct {
+		ID             string `json:"id"`
+		OwnedBy        string `json:"owned_by"`
+		ContextLength  int    `json:"context_length"`
+		ContextWindow  int    `json:"context_window"`
+		MaxModelLen    int    `json:"max_model_len"`
+		MaxInputTokens int    `json:"max_input_tokens"`
+	} `json:"data"`
+}
+
+func (p *openAIProvider) newModelsRequest(ctx context.Context) (*http.Request, error) {
+	url, err := listingURL(p.ep.APIEndpoint, []string{"/chat/completions", "/completions"}, "/models")
+	if err != nil {
+		return nil, err
+	}
+	req, err := newGetRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.ep.APIToken)
+	return req, nil
+}
+
+func (p *openAIProvider) parseModels(body []byte) ([]Model, error) {
+	var resp openAIModels
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]Model, len(resp.Data))
+	for i, m := range resp.Data {
+		models[i] = Model{ID: m.ID, Owner: m.OwnedBy}
+		for _, n := range []int{m.ContextLength, m.ContextWindow, m.MaxModelLen, m.MaxInputTokens} {
+			if n > 0 {
+				models[i].ContextLength = n
+				break
+			}
+		}
+	}
+	return models, nil
+}
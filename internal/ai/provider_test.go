@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+func TestAnthropicProvider_GenerateCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "test-token" {
+			t.Error("unexpected x-api-key header")
+		}
+		if r.Header.Get("anthropic-version") != anthropicVersion {
+			t.Error("missing anthropic-version header")
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("Anthropic requests must not send a Bearer token")
+		}
+
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.System == "" {
+			t.Error("expected system prompt in top-level field")
+		}
+		for _, m := range req.Messages {
+			if m.Role == "system" {
+				t.Error("system message must not be sent in messages")
+			}
+		}
+		if req.MaxTokens == 0 {
+			t.Error("expected max_tokens to be set")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"content": [{"type": "text", "text": "ls -la"}]}`)
+	}))
+	defer server.Close()
+
+	cmd, err := newTestClient(server.URL, config.ProviderAnthropic).GenerateCommand(testContext(t), "list all files", "")
+	if err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if cmd != "ls -la" {
+		t.Errorf("unexpected command: %q, want %q", cmd, "ls -la")
+	}
+}
+
+func TestAnthropicProvider_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\": \"message_start\"}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"delta\": {\"type\": \"text_delta\", \"text\": \"df\"}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"delta\": {\"type\": \"text_delta\", \"text\": \" -h\"}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\": \"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+
+	cmd, err := newTestClient(server.URL, config.ProviderAnthropic).GenerateCommandStream(testContext(t), "disk usage", "", nil)
+	if err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+	if cmd != "df -h" {
+		t.Errorf("unexpected command: %q, want %q", cmd, "df -h")
+	}
+}
+
+func TestOllamaProvider_GenerateCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if stream, ok := raw["stream"]; !ok || stream != false {
+			t.Errorf("expected explicit \"stream\": false, got %v", raw["stream"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"message": {"role": "assistant", "content": "uptime"}, "done": true}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOllama)
+	client.cfg.APIToken = "" // Ollama works without a token
+
+	cmd, err := client.GenerateCommand(testContext(t), "how long has the system been up", "")
+	if err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if cmd != "uptime" {
+		t.Errorf("unexpected command: %q, want %q", cmd, "uptime")
+	}
+}
+
+func TestOllamaProvider_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"message": {"content": "free"}, "done": false}`)
+		fmt.Fprintln(w, `{"message": {"content": " -m"}, "done": false}`)
+		fmt.Fprintln(w, `{"message": {"content": ""}, "done": true}`)
+	}))
+	defer server.Close()
+
+	var streamed strings.Builder
+	cmd, err := newTestClient(server.URL, config.ProviderOllama).GenerateCommandStream(testContext(t), "memory usage", "", func(token string) {
+		streamed.WriteString(token)
+	})
+	if err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+	if cmd != "free -m" || streamed.String() != "free -m" {
+		t.Errorf("got command %q, streamed %q; want %q", cmd, streamed.String(), "free -m")
+	}
+}
+
+func TestGeminiProvider_GenerateCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-1.5-flash:generateContent" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-token" {
+			t.Error("unexpected x-goog-api-key header")
+		}
+
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.SystemInstruction == nil {
+			t.Error("expected systemInstruction to be set")
+		}
+		if len(req.Contents) != 1 || req.Contents[0].Role != "user" {
+			t.Errorf("unexpected contents: %+v", req.Contents)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "whoami"}]}}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL+"/v1beta", config.ProviderGemini)
+	client.cfg.Model = "gemini-1.5-flash"
+
+	cmd, err := client.GenerateCommand(testContext(t), "current user", "")
+	if err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if cmd != "whoami" {
+		t.Errorf("unexpected command: %q, want %q", cmd, "whoami")
+	}
+}
+
+func TestGeminiProvider_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected streaming URL: %s", r.URL)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \"date\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \" -u\"}]}}]}\n\n")
+	}))
+	defer server.Close()
+
+	cmd, err := newTestClient(server.URL, config.ProviderGemini).GenerateCommandStream(testContext(t), "utc time", "", nil)
+	if err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+	if cmd != "date -u" {
+		t.Errorf("unexpected command: %q, want %q", cmd, "date -u")
+	}
+}
+
+func TestGeminiProvider_URL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		stream   bool
+		expected string
+	}{
+		{"https://g.example/v1beta", false, "https://g.example/v1beta/models/m:generateContent"},
+		{"https://g.example/v1beta/", true, "https://g.example/v1beta/models/m:streamGenerateContent?alt=sse"},
+		{"https://g.example/v1beta/models/x:generateContent", false, "https://g.example/v1beta/models/x:generateContent"},
+		{"https://g.example/v1beta/models/x:generateContent", true, "https://g.example/v1beta/models/x:streamGenerateContent?alt=sse"},
+	}
+
+	for _, tt := range tests {
+		p := &geminiProvider{ep: config.Endpoint{APIEndpoint: tt.endpoint, Model: "m"}}
+		if got := p.url(tt.stream); got != tt.expected {
+			t.Errorf("url(%q, %v) = %q, want %q", tt.endpoint, tt.stream, got, tt.expected)
+		}
+	}
+}
+
+// newTestClient returns a client for the given fake server and provider.
+func newTestClient(endpoint, provider string) *Client {
+	return NewClient(&config.Config{
+		APIEndpoint: endpoint,
+		APIToken:    "test-token",
+		Model:       "test-model",
+		Provider:    provider,
+	})
+}
+
+// testContext returns a context that is cancelled when the test ends.
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
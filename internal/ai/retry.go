@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+// apiError describes a failed API call. status is 0 when the request never
+// got an HTTP response (connection refused, DNS failure, reset, ...).
+type apiError struct {
+	status     int
+	retryAfter time.Duration
+	msg        string
+	err        error
+}
+
+func (e *apiError) Error() string {
+	if e.err != nil {
+		return e.msg + ": " + e.err.Error()
+	}
+	return e.msg
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+// transient reports whether repeating the request may succeed.
+func (e *apiError) transient() bool {
+	switch e.status {
+	case 0, http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryPolicy controls how often and how long the client waits between
+// attempts. Generating a command has no side effects, so every request can
+// safely be repeated.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// newRetryPolicy reads the retry settings from cfg.
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	return retryPolicy{
+		maxRetries: cfg.MaxRetries,
+		baseDelay:  cfg.RetryBaseDelayDuration(),
+		maxDelay:   cfg.RetryMaxDelayDuration(),
+	}
+}
+
+// delay returns how long to wait before retry number attempt+1. A wait
+// requested by the server is honored in full, since retrying earlier only
+// burns attempts on further rate-limit errors; sleepContext gives up when
+// it would outlast the request's deadline. Otherwise the jittered
+// exponential backoff applies, capped at maxDelay.
+func (p retryPolicy) delay(attempt int, serverDelay time.Duration) time.Duration {
+	if serverDelay > 0 {
+		return serverDelay
+	}
+
+	backoff := p.baseDelay
+	for i := 0; i < attempt && backoff < p.maxDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > p.maxDelay {
+		backoff = p.maxDelay
+	}
+
+	// Equal jitter: half fixed, half random, so concurrent clients spread out.
+	half := backoff / 2
+	if half <= 0 {
+		return backoff
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// maxResetSeconds is the longest bare x-ratelimit-reset value read as a
+// number of seconds. Larger values are Unix times; one that already passed
+// is ignored.
+const maxResetSeconds = 24 * 60 * 60
+
+// retryAfter extracts a server-requested wait from Retry-After (seconds or
+// HTTP date) or x-ratelimit-reset-* headers (durations such as "6m0s",
+// seconds or Unix times). It returns 0 when none is present.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			if d := t.Sub(now); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+
+	// Wait for whichever limit resets last.
+	var wait time.Duration
+	for _, key := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens", "x-ratelimit-reset"} {
+		v := strings.TrimSpace(h.Get(key))
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			d = resetDelay(v, now)
+		}
+		if d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// resetDelay reads a bare x-ratelimit-reset number, which some providers
+// send as seconds to wait and others as the Unix time of the reset.
+func resetDelay(v string, now time.Time) time.Duration {
+	secs, err := strconv.ParseFloat(v, 64)
+	switch {
+	case err != nil || secs < 0:
+		return 0
+	case secs > float64(now.Unix()):
+		return time.Unix(0, int64(secs*float64(time.Second))).Sub(now)
+	case secs > maxResetSeconds:
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// sleepContext waits for d and reports whether the wait completed. It
+// returns false immediately if the wait would outlast ctx's deadline.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
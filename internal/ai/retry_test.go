@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+func TestGenerateCommand_RetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			resp := map[string]interface{}{
+				"choices": []map[string]interface{}{
+					{"message": map[string]string{"content": "ls -la"}},
+				},
+			}
+			json.NewEncoder(w).Encode(resp)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(retryConfig(server.URL, 3))
+
+	cmd, err := client.GenerateCommand(testContext(t), "list all files", "")
+	if err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if cmd != "ls -la" {
+		t.Errorf("unexpected command: %q, want %q", cmd, "ls -la")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestGenerateCommand_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(retryConfig(server.URL, 2))
+
+	if _, err := client.GenerateCommand(testContext(t), "list files", ""); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if calls != 3 {
+		t.Errorf("expected 1 attempt + 2 retries, got %d", calls)
+	}
+}
+
+func TestGenerateCommand_DoesNotRetryAuthErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient(retryConfig(server.URL, 3))
+
+	if _, err := client.GenerateCommand(testContext(t), "list files", ""); err == nil {
+		t.Fatal("expected error for unauthorized request")
+	}
+	if calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestGenerateCommand_RetryRespectsDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// retry_max_delay does not shorten a wait the server asked for.
+	client := NewClient(retryConfig(server.URL, 3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.GenerateCommand(ctx, "list files", ""); err == nil {
+		t.Fatal("expected rate limit error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to give up immediately, waited %v", elapsed)
+	}
+}
+
+func TestGenerateCommand_CancelledDuringBackoff(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		requested <- struct{}{}
+	}))
+	defer server.Close()
+
+	client := NewClient(retryConfig(server.URL, 3))
+	ctx, cancel := context.WithCancel(testContext(t))
+	defer cancel()
+
+	// Interrupt while the client waits out Retry-After, as Ctrl-C would.
+	go func() {
+		<-requested
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := client.GenerateCommand(ctx, "list files", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the wait to end on cancel, took %v", elapsed)
+	}
+}
+
+func TestGenerateCommand_RetriesPastEpochReset(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// A reset time that already passed must not end the retries.
+			w.Header().Set("x-ratelimit-reset", strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "ls"}}},
+		})
+	}))
+	defer server.Close()
+
+	cmd, err := NewClient(retryConfig(server.URL, 2)).GenerateCommand(testContext(t), "list files", "")
+	if err != nil || cmd != "ls" || calls != 2 {
+		t.Errorf("GenerateCommand = %q, %v after %d attempts", cmd, err, calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected time.Duration
+	}{
+		{"none", nil, 0},
+		{"seconds", map[string]string{"Retry-After": "7"}, 7 * time.Second},
+		{"http date", map[string]string{"Retry-After": now.Add(90 * time.Second).Format(http.TimeFormat)}, 90 * time.Second},
+		{"past date", map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, 0},
+		{"ratelimit reset", map[string]string{"x-ratelimit-reset-requests": "1s", "x-ratelimit-reset-tokens": "6m0s"}, 6 * time.Minute},
+		{"ratelimit seconds", map[string]string{"x-ratelimit-reset": "2.5"}, 2500 * time.Millisecond},
+		{"ratelimit epoch", map[string]string{"x-ratelimit-reset": strconv.FormatInt(now.Add(40*time.Second).Unix(), 10)}, 40 * time.Second},
+		{"ratelimit past epoch", map[string]string{"x-ratelimit-reset": strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)}, 0},
+	}
+
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.headers {
+			h.Set(k, v)
+		}
+		if got := retryAfter(h, now); got != tt.expected {
+			t.Errorf("%s: retryAfter = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{maxRetries: 5, baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+
+	for attempt := 0; attempt < 6; attempt++ {
+		d := policy.delay(attempt, 0)
+		if d <= 0 || d > policy.maxDelay {
+			t.Errorf("delay(%d) = %v, want within (0, %v]", attempt, d, policy.maxDelay)
+		}
+	}
+
+	if d := policy.delay(0, 400*time.Millisecond); d != 400*time.Millisecond {
+		t.Errorf("server delay not honored: got %v", d)
+	}
+	if d := policy.delay(0, time.Minute); d != time.Minute {
+		t.Errorf("server delay beyond maxDelay not honored: got %v", d)
+	}
+}
+
+// retryConfig returns a config for server with fast retries.
+func retryConfig(endpoint string, maxRetries int) *config.Config {
+	return &config.Config{
+		APIEndpoint:    endpoint,
+		APIToken:       "test-token",
+		Model:          "gpt-4o-mini",
+		MaxRetries:     maxRetries,
+		RetryBaseDelay: "1ms",
+		RetryMaxDelay:  "5ms",
+	}
+}
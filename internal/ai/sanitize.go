@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Models do not always answer with a bare command. sanitizeCommand runs a
+// reply through the steps below to extract the most plausible single
+// command, and returns the text it had to throw away besides reasoning.
+
+var (
+	// reasoningBlock matches the <think> blocks of reasoning models.
+	reasoningBlock = regexp.MustCompile(`(?is)<(think|thinking|reasoning)>.*?</(think|thinking|reasoning)>`)
+	// reasoningEnd matches a closing tag whose opening tag the server
+	// already stripped.
+	reasoningEnd = regexp.MustCompile(`(?is)^.*</(think|thinking|reasoning)>`)
+	// reasoningOpen matches a block cut off before its closing tag.
+	reasoningOpen = regexp.MustCompile(`(?is)<(think|thinking|reasoning)>.*$`)
+
+	// fencedBlock matches a markdown code block; the info string such as
+	// "bash" is skipped.
+	fencedBlock = regexp.MustCompile("(?s)```[^\n`]*\n(.*?)\n?```")
+
+	// commandLabel matches a "Command:" style prefix on the first line.
+	commandLabel = regexp.MustCompile(`(?i)^(?:shell\s+|bash\s+|powershell\s+)?(?:command|cmd)\s*:\s*`)
+	// promptMarker matches a copied prompt such as "$ " or "PS C:\> ".
+	promptMarker = regexp.MustCompile(`^(?:\$ |PS(?: [A-Za-z]:\\[^>]*)?> )`)
+
+	// proseOnly matches text without any character typical of commands,
+	// such as the sentence around an inline code span.
+	proseOnly = regexp.MustCompile(`^[\p{L}\p{N}\s.,:;!?'()]*$`)
+)
+
+// sanitize extracts the command from a model reply and records the text
+// it had to discard for Discarded.
+func (c *Client) sanitize(reply string) string {
+	command, discarded := sanitizeCommand(reply)
+	c.discarded = append(c.discarded, discarded...)
+	return command
+}
+
+// Discarded returns the lines of extra text, such as explanations, that
+// were dropped from the model's replies to get at the command.
+func (c *Client) Discarded() []string {
+	return c.discarded
+}
+
+// sanitizeCommand extracts a single command from reply. It removes
+// reasoning blocks, picks the first fenced block or the first paragraph
+// that is not an introduction, strips "Command:" labels and copied shell
+// prompts, and unwraps inline code. discarded holds the lines of other
+// text that were dropped.
+func sanitizeCommand(reply string) (command string, discarded []string) {
+	text := stripReasoning(reply)
+
+	if block, rest, ok := firstFencedBlock(text); ok {
+		text = block
+		discarded = appendLines(discarded, rest)
+	} else {
+		var rest string
+		text, rest = firstParagraph(text)
+		discarded = appendLines(discarded, rest)
+	}
+
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	lines[0] = commandLabel.ReplaceAllString(strings.TrimSpace(lines[0]), "")
+	for i, line := range lines {
+		lines[i] = promptMarker.ReplaceAllString(line, "")
+	}
+	command = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	if code, prose, ok := inlineCode(command); ok {
+		command = code
+		discarded = appendLines(discarded, prose)
+	}
+	return command, discarded
+}
+
+// stripReasoning removes reasoning blocks, including a dangling closing
+// tag and a block that was cut off before it ended.
+func stripReasoning(s string) string {
+	s = reasoningBlock.ReplaceAllString(s, "")
+	s = reasoningEnd.ReplaceAllString(s, "")
+	s = reasoningOpen.ReplaceAllString(s, "")
+	return strings.TrimSpace(s)
+}
+
+// unfence returns s without the markdown code fences around it, for
+// replies that are prose rather than a command. Text with anything outside
+// a single fenced block is returned unchanged.
+func unfence(s string) string {
+	s = strings.TrimSpace(s)
+	loc := fencedBlock.FindStringSubmatchIndex(s)
+	if loc == nil || loc[0] != 0 || loc[1] != len(s) {
+		return s
+	}
+	return strings.TrimSpace(s[loc[2]:loc[3]])
+}
+
+// firstFencedBlock returns the content of the first markdown code block
+// and everything around it, including further blocks.
+func firstFencedBlock(s string) (block, rest string, ok bool) {
+	loc := fencedBlock.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return "", "", false
+	}
+	block = s[loc[2]:loc[3]]
+	rest = s[:loc[0]] + "\n" + s[loc[1]:]
+	return block, rest, true
+}
+
+// firstParagraph returns the first blank-line separated paragraph of s
+// that is not an introduction such as "Here is the command:", and the
+// remaining text. Introduction lines at the start of the paragraph are
+// returned as part of the rest.
+func firstParagraph(s string) (paragraph, rest string) {
+	var paragraphs []string
+	for _, p := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	if len(paragraphs) == 0 {
+		return "", ""
+	}
+
+	var dropped []string
+	for len(paragraphs) > 1 && isIntroduction(paragraphs[0]) {
+		dropped = append(dropped, paragraphs[0])
+		paragraphs = paragraphs[1:]
+	}
+
+	lines := strings.Split(paragraphs[0], "\n")
+	for len(lines) > 1 && isIntroduction(lines[0]) {
+		dropped = append(dropped, lines[0])
+		lines = lines[1:]
+	}
+
+	dropped = append(dropped, paragraphs[1:]...)
+	return strings.Join(lines, "\n"), strings.Join(dropped, "\n")
+}
+
+// isIntroduction reports whether text is a lead-in to the command, like
+// "To list the files, run:".
+func isIntroduction(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasSuffix(text, ":") && !strings.Contains(text, "\n") && !commandLabel.MatchString(text)
+}
+
+// inlineCode unwraps a command given as inline code, either on its own
+// ("`ls -la`", "```ls -la```") or within a sentence ("Use `ls -la` to
+// list them."). A single line with exactly one code span qualifies, so
+// PowerShell's backtick escapes are left alone.
+func inlineCode(s string) (code, prose string, ok bool) {
+	if strings.Contains(s, "\n") {
+		return "", "", false
+	}
+	if len(s) > 6 && strings.HasPrefix(s, "```") && strings.HasSuffix(s, "```") && strings.Count(s, "`") == 6 {
+		return strings.TrimSpace(s[3 : len(s)-3]), "", true
+	}
+	if strings.Count(s, "`") != 2 {
+		return "", "", false
+	}
+	start := strings.Index(s, "`")
+	end := strings.LastIndex(s, "`")
+	code = strings.TrimSpace(s[start+1 : end])
+	prose = strings.Join(strings.Fields(s[:start]+" "+s[end+1:]), " ")
+	if code == "" || !proseOnly.MatchString(prose) {
+		return "", "", false
+	}
+	return code, prose, true
+}
+
+// appendLines appends the non-blank lines of text to lines.
+func appendLines(lines []string, text string) []string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
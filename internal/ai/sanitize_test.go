@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		command   string
+		discarded []string
+	}{
+		{"bare command", "ls -la", "ls -la", nil},
+		{"surrounding whitespace", "\n  ls -la \n", "ls -la", nil},
+		{"fenced", "```\nls -la\n```", "ls -la", nil},
+		{"fenced with language", "```bash\nfind . -name '*.go'\n```", "find . -name '*.go'", nil},
+		{"fenced on one line", "```ls -la```", "ls -la", nil},
+		{
+			"fenced with explanation",
+			"Here is the command:\n```sh\ndu -sh * | sort -h\n```\nThis sorts directories by size.",
+			"du -sh * | sort -h",
+			[]string{"Here is the command:", "This sorts directories by size."},
+		},
+		{
+			"multiple fenced blocks",
+			"```bash\napt update\n```\nor on Fedora:\n```bash\ndnf check-update\n```",
+			"apt update",
+			[]string{"or on Fedora:", "```bash", "dnf check-update", "```"},
+		},
+		{"shell prompt", "$ ls -la", "ls -la", nil},
+		{"shell prompt in fence", "```\n$ git status\n```", "git status", nil},
+		{"powershell prompt", `PS C:\Users\me> Get-ChildItem`, "Get-ChildItem", nil},
+		{"powershell short prompt", "PS> Get-Process", "Get-Process", nil},
+		{"command label", "Command: ls -la", "ls -la", nil},
+		{"lowercase label", "command:   df -h", "df -h", nil},
+		{"label on its own line", "Command:\ntar -czf logs.tar.gz logs", "tar -czf logs.tar.gz logs", nil},
+		{"inline code", "`ls -la`", "ls -la", nil},
+		{"label and inline code", "Command: `ls -la`", "ls -la", nil},
+		{"inline code in sentence", "Use `ls -la` to list all files.", "ls -la", []string{"Use to list all files."}},
+		{"powershell backtick escapes", "Write-Host `\"quoted`\"", "Write-Host `\"quoted`\"", nil},
+		{
+			"trailing explanation",
+			"find . -size +100M\n\nThis finds all files larger than 100MB.\nAdd -delete to remove them.",
+			"find . -size +100M",
+			[]string{"This finds all files larger than 100MB.", "Add -delete to remove them."},
+		},
+		{
+			"introduction paragraph",
+			"Sure! To do that, run:\n\nps aux | grep node",
+			"ps aux | grep node",
+			[]string{"Sure! To do that, run:"},
+		},
+		{
+			"introduction line",
+			"You can use the following command:\nfree -h",
+			"free -h",
+			[]string{"You can use the following command:"},
+		},
+		{"multi-line command", "docker run \\\n  -it ubuntu", "docker run \\\n  -it ubuntu", nil},
+		{"think block", "<think>\nThe user wants files.\n\nls is fine.\n</think>\n\nls -la", "ls -la", nil},
+		{"think block then fence", "<think>use find</think>\n```bash\nfind . -type f\n```", "find . -type f", nil},
+		{"dangling closing tag", "The user wants to list.\n</think>\nls -la", "ls -la", nil},
+		{"unterminated think block", "<think>still thinking about it", "", nil},
+		{"comment kept", "ls -la # long listing", "ls -la # long listing", nil},
+		{"empty", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, discarded := sanitizeCommand(tt.reply)
+			if command != tt.command {
+				t.Errorf("command = %q, want %q", command, tt.command)
+			}
+			if !reflect.DeepEqual(discarded, tt.discarded) {
+				t.Errorf("discarded = %q, want %q", discarded, tt.discarded)
+			}
+		})
+	}
+}
+
+func TestUnfence(t *testing.T) {
+	tests := map[string]string{
+		"Lists files.":           "Lists files.",
+		"```\nLists files.\n```": "Lists files.",
+		"```text\nLists all files.\n\nHidden ones too.\n```": "Lists all files.\n\nHidden ones too.",
+		"Run `ls`, or ```\nls -a\n``` for more":              "Run `ls`, or ```\nls -a\n``` for more",
+	}
+	for input, want := range tests {
+		if got := unfence(input); got != want {
+			t.Errorf("unfence(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
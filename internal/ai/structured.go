@@ -0,0 +1,219 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Risk levels reported in CommandResult.RiskLevel, matching the risk table
+// in docs/security.md.
+const (
+	RiskSafe     = "safe"
+	RiskLow      = "low"
+	RiskMedium   = "medium"
+	RiskHigh     = "high"
+	RiskCritical = "critical"
+	RiskUnknown  = "unknown"
+)
+
+// CommandResult is a generated command together with the model's own
+// assessment of it.
+type CommandResult struct {
+	Command      string   `json:"command"`
+	Explanation  string   `json:"explanation"`
+	RiskLevel    string   `json:"risk_level"`
+	RequiresSudo bool     `json:"requires_sudo"`
+	Assumptions  []string `json:"assumptions"`
+
+	// Unstructured is set when the model answered with a bare command
+	// instead of the JSON object, so only Command is meaningful.
+	Unstructured bool `json:"unstructured,omitempty"`
+}
+
+// jsonSchema describes the JSON object a structured request expects. It is
+// sent as response_format (or the provider's equivalent) where supported.
+type jsonSchema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+// commandResultSchema is the JSON schema for CommandResult.
+var commandResultSchema = &jsonSchema{
+	Name: "command_result",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command":       map[string]interface{}{"type": "string"},
+			"explanation":   map[string]interface{}{"type": "string"},
+			"risk_level":    map[string]interface{}{"type": "string", "enum": []string{RiskSafe, RiskLow, RiskMedium, RiskHigh, RiskCritical}},
+			"requires_sudo": map[string]interface{}{"type": "boolean"},
+			"assumptions":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required":             []string{"command", "explanation", "risk_level", "requires_sudo", "assumptions"},
+		"additionalProperties": false,
+	},
+}
+
+// structuredSystemPrompt builds the system prompt for structured output.
+func structuredSystemPrompt(osName, shellType string) string {
+	return fmt.Sprintf(
+		"You are a shell command generator. Generate a single, valid shell command based on the user's description. "+
+			"The command should work in %s on %s. "+
+			"Respond with ONLY a JSON object, no markdown, with these fields: "+
+			`"command" (string, the command), "explanation" (string, one or two sentences), `+
+			`"risk_level" (one of "safe", "low", "medium", "high", "critical"), `+
+			`"requires_sudo" (boolean), "assumptions" (array of strings, e.g. tools or paths you assumed).`,
+		shellType, osName,
+	) + shellGuidance(shellType)
+}
+
+// GenerateStructured works like GenerateCommand but returns the command with
+// an explanation, risk assessment and the assumptions the model made. It asks
+// for schema-constrained JSON where the endpoint supports it and falls back
+// to extracting JSON from free text where it doesn't.
+func (c *Client) GenerateStructured(ctx context.Context, description, targetOS string) (*CommandResult, error) {
+	if err := c.cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	osName, shellType := c.resolveTarget(targetOS)
+	system, err := c.generationPrompt(structuredSystemPrompt(osName, shellType), osName, shellType, targetOS)
+	if err != nil {
+		return nil, err
+	}
+
+	req := completionRequest{
+		Messages: []Message{
+			{Role: "system", Content: c.withEnvironment(system, targetOS)},
+			{Role: "user", Content: fmt.Sprintf("Generate a single shell command for: %s", description)},
+		},
+		Schema: commandResultSchema,
+	}
+
+	content, err := c.completeJSON(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCommandResult(content)
+}
+
+// completeJSON sends a request carrying a Schema and returns the reply
+// text. If the endpoint rejects the structured output parameters, the
+// request is repeated without them and the prompt alone has to do.
+func (c *Client) completeJSON(ctx context.Context, req completionRequest) (string, error) {
+	choices, err := c.complete(ctx, req)
+	if req.Schema != nil && isRejectedParameter(err) {
+		req.Schema = nil
+		choices, err = c.complete(ctx, req)
+	}
+	if err != nil {
+		return "", err
+	}
+	return choices[0], nil
+}
+
+// isRejectedParameter reports whether err looks like the endpoint rejecting
+// an optional parameter, such as structured output or stream options,
+// rather than the request as a whole.
+func isRejectedParameter(err error) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.status == http.StatusBadRequest || apiErr.status == http.StatusUnprocessableEntity
+}
+
+// parseCommandResult decodes a model reply into a CommandResult. A reply
+// without any JSON object is treated as a bare command and marked
+// Unstructured.
+func parseCommandResult(content string) (*CommandResult, error) {
+	var result CommandResult
+
+	raw, ok := extractJSONObject(content)
+	if !ok || json.Unmarshal([]byte(raw), &result) != nil {
+		command, _ := sanitizeCommand(content)
+		if command == "" {
+			return nil, fmt.Errorf("API returned an empty response")
+		}
+		return &CommandResult{
+			Command:      command,
+			RiskLevel:    RiskUnknown,
+			Assumptions:  []string{},
+			Unstructured: true,
+		}, nil
+	}
+
+	result.Command, _ = sanitizeCommand(result.Command)
+	if result.Command == "" {
+		return nil, errNoCommand
+	}
+
+	result.RiskLevel = normalizeRisk(result.RiskLevel)
+	if result.Assumptions == nil {
+		result.Assumptions = []string{}
+	}
+	return &result, nil
+}
+
+// normalizeRisk maps a model-provided risk level onto the known levels.
+func normalizeRisk(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case RiskSafe, RiskLow, RiskMedium, RiskHigh, RiskCritical:
+		return level
+	case "none":
+		return RiskSafe
+	case "moderate":
+		return RiskMedium
+	case "dangerous", "severe":
+		return RiskCritical
+	}
+	return RiskUnknown
+}
+
+// extractJSONObject returns the first balanced {...} object in s, skipping
+// braces inside JSON strings. It tolerates prose, code fences and other
+// noise around the object.
+func extractJSONObject(s string) (string, bool) {
+	for start := strings.IndexByte(s, '{'); start >= 0; {
+		depth := 0
+		inString := false
+		escaped := false
+
+		for i := start; i < len(s); i++ {
+			ch := s[i]
+			switch {
+			case escaped:
+				escaped = false
+			case inString && ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = !inString
+			case inString:
+			case ch == '{':
+				depth++
+			case ch == '}':
+				depth--
+				if depth == 0 {
+					candidate := s[start : i+1]
+					if json.Valid([]byte(candidate)) {
+						return candidate, true
+					}
+					i = len(s) // not valid JSON; try the next opening brace
+				}
+			}
+		}
+
+		next := strings.IndexByte(s[start+1:], '{')
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+	return "", false
+}
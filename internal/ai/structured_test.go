@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestGenerateStructured_ResponseFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_schema" {
+			t.Fatalf("expected json_schema response_format, got %+v", req.ResponseFormat)
+		}
+
+		content := `{"command": "sudo apt update", "explanation": "Refreshes package lists.", "risk_level": "Medium", "requires_sudo": true, "assumptions": ["Debian-based system"]}`
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	result, err := client.GenerateStructured(testContext(t), "update packages", "linux")
+	if err != nil {
+		t.Fatalf("GenerateStructured failed: %v", err)
+	}
+	if result.Command != "sudo apt update" || !result.RequiresSudo || result.RiskLevel != RiskMedium {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(result.Assumptions) != 1 || result.Unstructured {
+		t.Errorf("expected one assumption, got %+v", result)
+	}
+}
+
+func TestGenerateStructured_FallsBackWithoutResponseFormat(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			if req.ResponseFormat == nil {
+				t.Error("expected first request to use response_format")
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"message": "response_format is not supported"}}`))
+			return
+		}
+		if req.ResponseFormat != nil {
+			t.Error("expected retry without response_format")
+		}
+
+		content := "Sure! Here you go:\n```json\n{\"command\": \"ls -la\", \"explanation\": \"Lists files {including hidden}.\", \"risk_level\": \"safe\", \"requires_sudo\": false, \"assumptions\": []}\n```"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{APIEndpoint: server.URL, APIToken: "test-token", Model: "gpt-4o-mini"})
+
+	result, err := client.GenerateStructured(testContext(t), "list files", "")
+	if err != nil {
+		t.Fatalf("GenerateStructured failed: %v", err)
+	}
+	if result.Command != "ls -la" || result.RiskLevel != RiskSafe {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 requests, got %d", calls)
+	}
+}
+
+func TestParseCommandResult_PlainCommand(t *testing.T) {
+	result, err := parseCommandResult("```bash\ndf -h\n```")
+	if err != nil {
+		t.Fatalf("parseCommandResult failed: %v", err)
+	}
+	if result.Command != "df -h" || result.RiskLevel != RiskUnknown || !result.Unstructured {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(result.Assumptions) != 0 {
+		t.Errorf("expected no assumptions, got %v", result.Assumptions)
+	}
+}
+
+func TestParseCommandResult_NoCommand(t *testing.T) {
+	if _, err := parseCommandResult(`{"command": "", "risk_level": "safe"}`); !errors.Is(err, errNoCommand) {
+		t.Errorf("expected errNoCommand, got %v", err)
+	}
+}
+
+func TestExtractJSONObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{`{"a": 1}`, `{"a": 1}`, true},
+		{`text before {"a": "}"} text after`, `{"a": "}"}`, true},
+		{`{"a": "say \"{\""}`, `{"a": "say \"{\""}`, true},
+		{`{not json} then {"b": {"c": 2}}`, `{"b": {"c": 2}}`, true},
+		{`no json here`, ``, false},
+		{`{"unterminated": 1`, ``, false},
+	}
+
+	for _, tt := range tests {
+		result, ok := extractJSONObject(tt.input)
+		if ok != tt.ok || result != tt.expected {
+			t.Errorf("extractJSONObject(%q) = %q, %v; want %q, %v", tt.input, result, ok, tt.expected, tt.ok)
+		}
+	}
+}
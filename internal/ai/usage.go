@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// costHeader is the response header in which LiteLLM reports the cost of a
+// request in USD.
+const costHeader = "x-litellm-response-cost"
+
+// Usage is the token usage reported by the endpoint for the requests a
+// Client made.
+type Usage struct {
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+
+	// Cost is the cost in USD reported by the endpoint itself, if any.
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+// TotalTokens returns the sum of prompt and completion tokens.
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// usageProvider is implemented by providers whose responses report token
+// usage.
+type usageProvider interface {
+	// parseUsage extracts the token counts from a complete response body or
+	// from the payload of one streamed chunk. ok is false if it carries none.
+	parseUsage(payload []byte) (u Usage, ok bool)
+}
+
+// merge overlays the non-zero counts of next onto u. Streams repeat the
+// running totals, so the latest non-zero value wins.
+func (u *Usage) merge(next Usage) {
+	if next.PromptTokens > 0 {
+		u.PromptTokens = next.PromptTokens
+	}
+	if next.CompletionTokens > 0 {
+		u.CompletionTokens = next.CompletionTokens
+	}
+}
+
+// Usage returns the usage accumulated over all requests the client made.
+// ok is false if no endpoint reported any.
+func (c *Client) Usage() (u Usage, ok bool) {
+	if c.usage == nil {
+		return Usage{}, false
+	}
+	return *c.usage, true
+}
+
+// addUsage adds the usage of one response to the client's total. header
+// is the response header, which may carry the endpoint's own cost.
+func (c *Client) addUsage(u Usage, reported bool, header http.Header) {
+	cost, hasCost := parseCost(header)
+	if !reported && !hasCost {
+		return
+	}
+
+	if c.usage == nil {
+		c.usage = &Usage{}
+	}
+	if c.answeredBy != nil {
+		c.usage.Model = c.answeredBy.Model
+	}
+	c.usage.PromptTokens += u.PromptTokens
+	c.usage.CompletionTokens += u.CompletionTokens
+	if hasCost {
+		if c.usage.Cost == nil {
+			c.usage.Cost = new(float64)
+		}
+		*c.usage.Cost += cost
+	}
+}
+
+// parseCost reads the cost header. ok is false if it is missing or invalid.
+func parseCost(header http.Header) (cost float64, ok bool) {
+	value := strings.TrimSpace(header.Get(costHeader))
+	if value == "" {
+		return 0, false
+	}
+	cost, err := strconv.ParseFloat(value, 64)
+	if err != nil || cost < 0 {
+		return 0, false
+	}
+	return cost, true
+}
+
+// responseUsage extracts the usage of a complete response body through p.
+func responseUsage(p provider, body []byte) (Usage, bool) {
+	up, ok := p.(usageProvider)
+	if !ok {
+		return Usage{}, false
+	}
+	return up.parseUsage(body)
+}
+
+// streamUsage extracts the usage carried by one line of a streamed
+// response, which is either a server-sent event or a bare JSON object.
+func streamUsage(p provider, line string) (Usage, bool) {
+	up, ok := p.(usageProvider)
+	if !ok {
+		return Usage{}, false
+	}
+	payload, ok := sseData(line)
+	if !ok {
+		payload = strings.TrimSpace(line)
+	}
+	if !strings.HasPrefix(payload, "{") {
+		return Usage{}, false
+	}
+	return up.parseUsage([]byte(payload))
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestUsage_CompleteWithCostHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-litellm-response-cost", "0.00042")
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ls"}}], "usage": {"prompt_tokens": 120, "completion_tokens": 4}}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	if _, ok := client.Usage(); ok {
+		t.Fatal("expected no usage before any request")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateCommand(testContext(t), "list", ""); err != nil {
+			t.Fatalf("GenerateCommand failed: %v", err)
+		}
+	}
+
+	u, ok := client.Usage()
+	if !ok {
+		t.Fatal("expected usage to be reported")
+	}
+	if u.Model != "test-model" || u.PromptTokens != 240 || u.CompletionTokens != 8 || u.TotalTokens() != 248 {
+		t.Errorf("unexpected usage: %+v", u)
+	}
+	if u.Cost == nil || *u.Cost != 0.00084 {
+		t.Errorf("expected summed cost 0.00084, got %v", u.Cost)
+	}
+}
+
+func TestUsage_OpenAIStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body chatRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.StreamOptions == nil || !body.StreamOptions.IncludeUsage {
+			t.Error("expected stream_options.include_usage in streamed request")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"pwd\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 30, \"completion_tokens\": 1}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	if _, err := client.GenerateCommandStream(testContext(t), "where am I", "", nil); err != nil {
+		t.Fatalf("GenerateCommandStream failed: %v", err)
+	}
+
+	u, ok := client.Usage()
+	if !ok || u.PromptTokens != 30 || u.CompletionTokens != 1 || u.Cost != nil {
+		t.Errorf("unexpected usage: %+v (ok=%v)", u, ok)
+	}
+}
+
+func TestUsage_NotReported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ls"}}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, config.ProviderOpenAI)
+	if _, err := client.GenerateCommand(testContext(t), "list", ""); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if u, ok := client.Usage(); ok {
+		t.Errorf("expected no usage, got %+v", u)
+	}
+}
+
+func TestStreamUsage_Providers(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        provider
+		lines    []string
+		expected Usage
+	}{
+		{
+			name: "anthropic",
+			p:    &anthropicProvider{},
+			lines: []string{
+				`event: message_start`,
+				`data: {"type": "message_start", "message": {"usage": {"input_tokens": 25, "output_tokens": 1}}}`,
+				`data: {"type": "content_block_delta", "delta": {"type": "text_delta", "text": "ls"}}`,
+				`data: {"type": "message_delta", "usage": {"output_tokens": 7}}`,
+			},
+			expected: Usage{PromptTokens: 25, CompletionTokens: 7},
+		},
+		{
+			name: "ollama",
+			p:    &ollamaProvider{},
+			lines: []string{
+				`{"message": {"content": "ls"}, "done": false}`,
+				`{"message": {"content": ""}, "done": true, "prompt_eval_count": 18, "eval_count": 3}`,
+			},
+			expected: Usage{PromptTokens: 18, CompletionTokens: 3},
+		},
+		{
+			name: "gemini",
+			p:    &geminiProvider{},
+			lines: []string{
+				`data: {"candidates": [{"content": {"parts": [{"text": "l"}]}}], "usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 1}}`,
+				`data: {"candidates": [{"content": {"parts": [{"text": "s"}]}}], "usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 2}}`,
+			},
+			expected: Usage{PromptTokens: 12, CompletionTokens: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		var got Usage
+		for _, line := range tt.lines {
+			if u, ok := streamUsage(tt.p, line); ok {
+				got.merge(u)
+			}
+		}
+		if got.PromptTokens != tt.expected.PromptTokens || got.CompletionTokens != tt.expected.CompletionTokens {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.expected)
+		}
+	}
+}
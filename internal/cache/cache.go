@@ -0,0 +1,305 @@
+// Package cache stores generated commands on disk, so that repeated
+// requests are answered without a round trip to the model.
+//
+// Every entry is a file of its own, named after the hash of its key and
+// replaced atomically, so several aiterm processes can read and write the
+// cache at once without locking. A file's modification time is its last
+// use, which drives the LRU eviction.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+// dirName is the directory under the config directory holding the entries.
+const dirName = "cache"
+
+// entrySuffix ends the file name of every entry; temporary files don't
+// have it.
+const entrySuffix = ".json"
+
+// Key identifies a request. Two requests with the same key are expected to
+// get the same answer.
+type Key struct {
+	// Prompt is the user's description; it is normalized before hashing.
+	Prompt string
+	// OS and Shell are the resolved target.
+	OS    string
+	Shell string
+	// Model names the endpoints and models that may answer.
+	Model string
+	// PromptVersion identifies the system prompt, which covers the prompt
+	// template, few-shot examples and environment context.
+	PromptVersion string
+	// N is the number of alternatives asked for.
+	N int
+}
+
+// Hash returns the hex-encoded SHA-256 of the key.
+func (k Key) Hash() string {
+	h := sha256.New()
+	for _, field := range []string{NormalizePrompt(k.Prompt), k.OS, k.Shell, k.Model, k.PromptVersion, fmt.Sprint(k.N)} {
+		// A separator that cannot occur in the fields keeps them apart.
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NormalizePrompt trims a prompt and collapses its whitespace, so that
+// trivially different spellings of a request share an entry.
+func NormalizePrompt(prompt string) string {
+	return strings.Join(strings.Fields(prompt), " ")
+}
+
+// Version returns a short hash of text, such as a system prompt, for use
+// as Key.PromptVersion.
+func Version(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Entry is a cached answer.
+type Entry struct {
+	Prompt   string    `json:"prompt"`
+	Commands []string  `json:"commands"`
+	Model    string    `json:"model,omitempty"`
+	Created  time.Time `json:"created"`
+	Hits     int       `json:"hits"`
+}
+
+// Store is the cache directory with its expiry and size policy.
+type Store struct {
+	dir        string
+	ttl        time.Duration
+	maxEntries int
+}
+
+// Dir returns the cache directory.
+func Dir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dirName), nil
+}
+
+// Open returns the store in Dir. Entries older than ttl are ignored; when
+// more than maxEntries are stored, the least recently used are evicted.
+func Open(ttl time.Duration, maxEntries int) (*Store, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, ttl: ttl, maxEntries: maxEntries}, nil
+}
+
+// path returns the file of the entry for key.
+func (s *Store) path(key Key) string {
+	return filepath.Join(s.dir, key.Hash()+entrySuffix)
+}
+
+// Get returns the entry for key. Expired entries are removed and reported
+// as missing. A hit counts as a use for the LRU order.
+func (s *Store) Get(key Key) (Entry, bool) {
+	path := s.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil || len(e.Commands) == 0 {
+		os.Remove(path)
+		return Entry{}, false
+	}
+	if s.expired(e, time.Now()) {
+		os.Remove(path)
+		return Entry{}, false
+	}
+
+	e.Hits++
+	// Rewriting the entry records the hit and refreshes its modification
+	// time; losing a concurrent hit count is harmless.
+	s.write(path, e)
+	return e, true
+}
+
+// Put stores e under key and evicts the least recently used entries
+// beyond the size cap.
+func (s *Store) Put(key Key, e Entry) error {
+	if e.Created.IsZero() {
+		e.Created = time.Now()
+	}
+	if err := s.write(s.path(key), e); err != nil {
+		return err
+	}
+	return s.evict()
+}
+
+// write replaces the file at path with e atomically, so concurrent readers
+// never see a partial entry.
+func (s *Store) write(path string, e Entry) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// expired reports whether e is older than the store's TTL.
+func (s *Store) expired(e Entry, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(e.Created) >= s.ttl
+}
+
+// file is an entry file with its last use.
+type file struct {
+	path    string
+	size    int64
+	lastUse time.Time
+}
+
+// files lists the entry files in dir. A missing directory has none.
+func files(dir string) ([]file, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var list []file
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), entrySuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed by another process in the meantime.
+			continue
+		}
+		list = append(list, file{
+			path:    filepath.Join(dir, entry.Name()),
+			size:    info.Size(),
+			lastUse: info.ModTime(),
+		})
+	}
+	return list, nil
+}
+
+// evict removes the least recently used entries beyond maxEntries.
+func (s *Store) evict() error {
+	if s.maxEntries <= 0 {
+		return nil
+	}
+	list, err := files(s.dir)
+	if err != nil || len(list) <= s.maxEntries {
+		return err
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].lastUse.Before(list[j].lastUse) })
+	for _, f := range list[:len(list)-s.maxEntries] {
+		// Another process may have evicted it already.
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to evict cache entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stats describes the contents of the cache.
+type Stats struct {
+	Entries int
+	Expired int
+	Bytes   int64
+	Hits    int
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Stats summarizes the entries in the store.
+func (s *Store) Stats() (Stats, error) {
+	list, err := files(s.dir)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var st Stats
+	now := time.Now()
+	for _, f := range list {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			continue
+		}
+		var e Entry
+		if json.Unmarshal(data, &e) != nil {
+			continue
+		}
+
+		st.Entries++
+		st.Bytes += f.size
+		st.Hits += e.Hits
+		if s.expired(e, now) {
+			st.Expired++
+		}
+		if st.Oldest.IsZero() || e.Created.Before(st.Oldest) {
+			st.Oldest = e.Created
+		}
+		if e.Created.After(st.Newest) {
+			st.Newest = e.Created
+		}
+	}
+	return st, nil
+}
+
+// Clear removes every entry and returns how many there were.
+func (s *Store) Clear() (int, error) {
+	list, err := files(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, f := range list {
+		if err := os.Remove(f.path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return removed, fmt.Errorf("failed to clear cache: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStore returns a store in a temporary home directory.
+func testStore(t *testing.T, ttl time.Duration, maxEntries int) *Store {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	s, err := Open(ttl, maxEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestKey_Hash(t *testing.T) {
+	base := Key{Prompt: "list  files\n", OS: "Linux", Shell: "bash", Model: "gpt-4o-mini", PromptVersion: "v1", N: 1}
+
+	same := base
+	same.Prompt = " list files"
+	if base.Hash() != same.Hash() {
+		t.Error("expected whitespace differences to share a key")
+	}
+
+	for _, change := range []func(*Key){
+		func(k *Key) { k.Prompt = "list dirs" },
+		func(k *Key) { k.Shell = "zsh" },
+		func(k *Key) { k.Model = "gpt-4o" },
+		func(k *Key) { k.PromptVersion = "v2" },
+		func(k *Key) { k.N = 3 },
+	} {
+		other := base
+		change(&other)
+		if other.Hash() == base.Hash() {
+			t.Errorf("expected %+v to get a different key", other)
+		}
+	}
+}
+
+func TestStore_GetPut(t *testing.T) {
+	s := testStore(t, time.Hour, 10)
+	key := Key{Prompt: "list files", OS: "Linux", Shell: "bash"}
+
+	if _, ok := s.Get(key); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	if err := s.Put(key, Entry{Prompt: "list files", Commands: []string{"ls"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := s.Get(key)
+	if !ok || e.Commands[0] != "ls" || e.Hits != 1 {
+		t.Fatalf("unexpected entry: %+v, %v", e, ok)
+	}
+	if e, _ = s.Get(key); e.Hits != 2 {
+		t.Errorf("expected hits to be counted, got %d", e.Hits)
+	}
+
+	// Expired entries are dropped.
+	if err := s.Put(key, Entry{Commands: []string{"ls"}, Created: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get(key); ok {
+		t.Error("expected an expired entry to miss")
+	}
+	if _, err := os.Stat(s.path(key)); !os.IsNotExist(err) {
+		t.Error("expected the expired entry to be removed")
+	}
+}
+
+func TestStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	s := testStore(t, 0, 2)
+	keys := []Key{{Prompt: "a"}, {Prompt: "b"}, {Prompt: "c"}}
+
+	for i, key := range keys[:2] {
+		if err := s.Put(key, Entry{Commands: []string{key.Prompt}}); err != nil {
+			t.Fatal(err)
+		}
+		past := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(s.path(key), past, past)
+	}
+
+	// Using "a" makes "b" the least recently used.
+	if _, ok := s.Get(keys[0]); !ok {
+		t.Fatal("expected a hit")
+	}
+	if err := s.Put(keys[2], Entry{Commands: []string{"c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[Key]bool{keys[0]: true, keys[1]: false, keys[2]: true} {
+		if _, ok := s.Get(key); ok != want {
+			t.Errorf("Get(%q) = %v, want %v", key.Prompt, ok, want)
+		}
+	}
+}
+
+func TestStore_StatsAndClear(t *testing.T) {
+	s := testStore(t, time.Hour, 0)
+
+	st, err := s.Stats()
+	if err != nil || st.Entries != 0 {
+		t.Fatalf("expected empty stats without a directory, got %+v, %v", st, err)
+	}
+
+	s.Put(Key{Prompt: "a"}, Entry{Commands: []string{"a"}})
+	s.Put(Key{Prompt: "b"}, Entry{Commands: []string{"b"}, Created: time.Now().Add(-2 * time.Hour)})
+	s.Get(Key{Prompt: "a"})
+
+	st, err = s.Stats()
+	if err != nil || st.Entries != 2 || st.Expired != 1 || st.Hits != 1 || st.Bytes == 0 {
+		t.Errorf("unexpected stats: %+v, %v", st, err)
+	}
+
+	if n, err := s.Clear(); err != nil || n != 2 {
+		t.Errorf("Clear() = %d, %v; want 2", n, err)
+	}
+	if st, _ := s.Stats(); st.Entries != 0 {
+		t.Errorf("expected an empty cache after clearing, got %+v", st)
+	}
+}
+
+func TestStore_ConcurrentWrites(t *testing.T) {
+	s := testStore(t, time.Hour, 5)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := Key{Prompt: fmt.Sprint(i % 8)}
+			s.Put(key, Entry{Commands: []string{fmt.Sprint(i)}})
+			s.Get(key)
+		}(i)
+	}
+	wg.Wait()
+
+	// A hit may revive an entry evicted concurrently; the next write
+	// enforces the cap again.
+	s.Put(Key{Prompt: "last"}, Entry{Commands: []string{"last"}})
+
+	list, err := files(s.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) > 5 {
+		t.Errorf("expected at most 5 entries, got %d", len(list))
+	}
+	for _, f := range list {
+		data, _ := os.ReadFile(f.path)
+		if len(data) == 0 {
+			t.Errorf("found a partial entry %s", f.path)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Package cassette records the HTTP exchanges of a session into a file and
+// replays them later, so that parsing of real provider responses can be
+// tested deterministically and offline.
+//
+// A cassette is a JSON file of request/response pairs. Credentials are
+// redacted before anything is written: the well-known auth headers and
+// every occurrence of the secrets passed to New.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Environment variables that enable a cassette for every API client.
+const (
+	// EnvPath is the cassette file.
+	EnvPath = "AITERM_CASSETTE"
+	// EnvMode is one of the Mode values; replay is the default.
+	EnvMode = "AITERM_CASSETTE_MODE"
+)
+
+// Mode selects what a cassette does with requests.
+type Mode string
+
+const (
+	// Record sends requests and appends each exchange to the cassette.
+	Record Mode = "record"
+	// Replay answers from the cassette and requires an exact match of
+	// method, URL and body.
+	Replay Mode = "replay"
+	// ReplayLenient answers from the cassette, matching method and URL path
+	// only (not host, query or body), in recording order.
+	ReplayLenient Mode = "lenient"
+)
+
+// Modes lists the valid modes.
+var Modes = []Mode{Record, Replay, ReplayLenient}
+
+// redacted replaces credentials in recorded exchanges.
+const redacted = "REDACTED"
+
+// sensitiveHeaders carry credentials and are never recorded.
+var sensitiveHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key", "Api-Key", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// ErrNoMatch is returned when a replayed request is not in the cassette.
+var ErrNoMatch = errors.New("no recorded interaction matches")
+
+// Interaction is one recorded exchange.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of a request.
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is the recorded part of a response.
+type Response struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body"`
+}
+
+// file is the on-disk form of a cassette.
+type file struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Transport is an http.RoundTripper that records to or replays from a
+// cassette file.
+type Transport struct {
+	path    string
+	mode    Mode
+	next    http.RoundTripper
+	secrets []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a transport for the cassette at path. Recording appends to
+// an existing cassette and sends requests through next; replaying loads
+// the cassette and never touches the network. secrets, such as API
+// tokens, are redacted wherever they appear.
+func New(path string, mode Mode, next http.RoundTripper, secrets ...string) (*Transport, error) {
+	if !isMode(mode) {
+		return nil, fmt.Errorf("unknown cassette mode %q — must be one of: record, replay, lenient", mode)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	t := &Transport{path: path, mode: mode, next: next}
+	for _, s := range secrets {
+		if s != "" {
+			t.secrets = append(t.secrets, s)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var f file
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		t.interactions = f.Interactions
+	case os.IsNotExist(err) && mode == Record:
+	default:
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	t.used = make([]bool, len(t.interactions))
+	return t, nil
+}
+
+// FromEnv wraps next in the cassette named by EnvPath, or returns next
+// unchanged when the variable is unset.
+func FromEnv(next http.RoundTripper, secrets ...string) (http.RoundTripper, error) {
+	path := os.Getenv(EnvPath)
+	if path == "" {
+		return next, nil
+	}
+	mode := Mode(strings.ToLower(os.Getenv(EnvMode)))
+	if mode == "" {
+		mode = Replay
+	}
+	return New(path, mode, next, secrets...)
+}
+
+// Interactions returns the exchanges in the cassette.
+func (t *Transport) Interactions() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Interaction(nil), t.interactions...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{
+		Method:  req.Method,
+		URL:     t.redact(req.URL.String()),
+		Headers: t.redactHeaders(req.Header),
+		Body:    t.redact(string(body)),
+	}
+
+	if t.mode == Record {
+		return t.record(req, recorded)
+	}
+	return t.replay(req, recorded)
+}
+
+// record sends req and appends the exchange to the cassette.
+func (t *Transport) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: t.redactHeaders(resp.Header),
+			Body:    t.redact(string(body)),
+		},
+	})
+	t.used = append(t.used, true)
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replay answers req with the first unused matching interaction.
+func (t *Transport) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, in := range t.interactions {
+		if t.used[i] || !t.matches(in.Request, recorded) {
+			continue
+		}
+		t.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Headers.Clone(),
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s: %w %s %s", t.path, ErrNoMatch, recorded.Method, recorded.URL)
+}
+
+// matches compares a recorded request with an incoming one according to
+// the mode.
+func (t *Transport) matches(recorded, incoming Request) bool {
+	if recorded.Method != incoming.Method {
+		return false
+	}
+	if t.mode == ReplayLenient {
+		return urlPath(recorded.URL) == urlPath(incoming.URL)
+	}
+	return recorded.URL == incoming.URL && sameBody(recorded.Body, incoming.Body)
+}
+
+// save writes the cassette atomically.
+func (t *Transport) save() error {
+	data, err := json.MarshalIndent(file{Interactions: t.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	dir := filepath.Dir(t.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(t.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// redact replaces every secret in s.
+func (t *Transport) redact(s string) string {
+	for _, secret := range t.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// redactHeaders copies h with credentials replaced.
+func (t *Transport) redactHeaders(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		copied := make([]string, len(values))
+		for i, v := range values {
+			copied[i] = t.redact(v)
+		}
+		out[name] = copied
+	}
+	for _, name := range sensitiveHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, redacted)
+		}
+	}
+	return out
+}
+
+// readBody reads and restores the request body.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// sameBody compares two bodies, as JSON values when both are JSON so that
+// formatting differences don't matter.
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// urlPath returns the path of a URL, without host and query.
+func urlPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Path
+}
+
+// isMode reports whether m is a valid mode.
+func isMode(m Mode) bool {
+	for _, valid := range Modes {
+		if m == valid {
+			return true
+		}
+	}
+	return false
+}
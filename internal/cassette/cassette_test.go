@@ -0,0 +1,172 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// post sends body to url through rt and returns the response body.
+func post(t *testing.T, rt http.RoundTripper, url, body string) (string, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), nil
+}
+
+// record captures two exchanges with a test server into a new cassette.
+func record(t *testing.T) (path, serverURL string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=abc")
+		io.WriteString(w, "echo "+string(body)+" key=sk-secret")
+	}))
+	t.Cleanup(server.Close)
+
+	path = filepath.Join(t.TempDir(), "session.json")
+	rt, err := New(path, Record, nil, "sk-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{`{"n": 1}`, `{"n": 2}`} {
+		got, err := post(t, rt, server.URL+"/v1/chat?key=sk-secret", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The caller sees the real response.
+		if !strings.Contains(got, "sk-secret") {
+			t.Errorf("recording changed the response: %q", got)
+		}
+	}
+	return path, server.URL
+}
+
+func TestRecord_Redacts(t *testing.T) {
+	path, _ := record(t)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-secret") || strings.Contains(string(data), "session=abc") {
+		t.Errorf("cassette leaks credentials:\n%s", data)
+	}
+
+	rt, err := New(path, Replay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactions := rt.Interactions()
+	if len(interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(interactions))
+	}
+	first := interactions[0]
+	if first.Request.Headers.Get("Authorization") != redacted || !strings.HasSuffix(first.Request.URL, "key=REDACTED") {
+		t.Errorf("request not redacted: %+v", first.Request)
+	}
+	if first.Response.Body != `echo {"n": 1} key=REDACTED` {
+		t.Errorf("unexpected response body %q", first.Response.Body)
+	}
+}
+
+func TestReplay_Strict(t *testing.T) {
+	path, serverURL := record(t)
+
+	rt, err := New(path, Replay, nil, "sk-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serverURL + "/v1/chat?key=sk-secret"
+
+	// Bodies match as JSON, regardless of order or formatting.
+	got, err := post(t, rt, url, `{"n":2}`)
+	if err != nil || got != `echo {"n": 2} key=REDACTED` {
+		t.Errorf("replay = %q, %v", got, err)
+	}
+	if _, err := post(t, rt, url, `{"n": 3}`); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch for another body, got %v", err)
+	}
+	if _, err := post(t, rt, "http://elsewhere/v1/chat?key=sk-secret", `{"n": 1}`); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch for another host, got %v", err)
+	}
+
+	// Each interaction answers once.
+	if _, err := post(t, rt, url, `{"n": 2}`); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected a used interaction not to replay, got %v", err)
+	}
+}
+
+func TestReplay_Lenient(t *testing.T) {
+	path, _ := record(t)
+
+	rt, err := New(path, ReplayLenient, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Host, query and body are ignored; interactions replay in order.
+	for _, want := range []string{`echo {"n": 1} key=REDACTED`, `echo {"n": 2} key=REDACTED`} {
+		got, err := post(t, rt, "http://ci.invalid/v1/chat", `{"anything": true}`)
+		if err != nil || got != want {
+			t.Errorf("replay = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := post(t, rt, "http://ci.invalid/v1/chat", "{}"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch once the cassette is used up, got %v", err)
+	}
+	if _, err := post(t, rt, "http://ci.invalid/v1/other", "{}"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch for another path, got %v", err)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(filepath.Join(dir, "missing.json"), Replay, nil); err == nil {
+		t.Error("expected replaying a missing cassette to fail")
+	}
+	if _, err := New(filepath.Join(dir, "new.json"), "rewind", nil); err == nil {
+		t.Error("expected an unknown mode to fail")
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	os.WriteFile(broken, []byte("{"), 0600)
+	if _, err := New(broken, Record, nil); err == nil {
+		t.Error("expected a malformed cassette to fail")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EnvPath, "")
+	if rt, err := FromEnv(http.DefaultTransport); err != nil || rt != http.DefaultTransport {
+		t.Errorf("expected the transport unchanged without %s", EnvPath)
+	}
+
+	path, _ := record(t)
+	t.Setenv(EnvPath, path)
+	t.Setenv(EnvMode, "LENIENT")
+	rt, err := FromEnv(http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct, ok := rt.(*Transport); !ok || ct.mode != ReplayLenient {
+		t.Errorf("expected a lenient cassette, got %#v", rt)
+	}
+}
@@ -1,22 +1,188 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"aiterm/internal/prompt"
+	"aiterm/internal/shell"
+)
+
+// Supported values for the provider key.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+	ProviderGemini    = "gemini"
 )
 
+// Providers lists the supported provider backends.
+var Providers = []string{ProviderOpenAI, ProviderAnthropic, ProviderOllama, ProviderGemini}
+
 // Config represents the application configuration.
 type Config struct {
 	APIEndpoint string `json:"api_endpoint"`
 	APIToken    string `json:"api_token"`
 	Model       string `json:"model"`
 	Shell       string `json:"shell"`
+	Provider    string `json:"provider"`
+
+	// Timeout limits a whole API call, retries included; ConnectTimeout
+	// limits establishing each connection, TLS handshake included.
+	Timeout        string `json:"timeout"`
+	ConnectTimeout string `json:"connect_timeout"`
+
+	MaxRetries     int    `json:"max_retries"`
+	RetryBaseDelay string `json:"retry_base_delay"`
+	RetryMaxDelay  string `json:"retry_max_delay"`
+
+	// Endpoints are tried in order after the primary endpoint above fails.
+	Endpoints        []Endpoint `json:"endpoints,omitempty"`
+	EndpointCooldown string     `json:"endpoint_cooldown"`
+
+	// DetectTools tells the model which common CLIs are installed. Tools
+	// adds names to the built-in list; results are cached for ToolsCacheTTL.
+	DetectTools   bool     `json:"detect_tools"`
+	Tools         []string `json:"tools,omitempty"`
+	ToolsCacheTTL string   `json:"tools_cache_ttl"`
+
+	// Transport configures how API requests reach the endpoints: proxy,
+	// trusted CAs, client certificate and pinning. Headers are added to
+	// every request.
+	Transport TransportConfig   `json:"transport"`
+	Headers   map[string]string `json:"headers,omitempty"`
+
+	// Context selects which facts about the local environment are sent
+	// along with prompts. Everything is off unless enabled.
+	Context ContextConfig `json:"context"`
+
+	// PromptTemplate is the path of a text/template file that replaces the
+	// built-in generation prompt; PromptVars are its custom variables.
+	PromptTemplate string            `json:"prompt_template,omitempty"`
+	PromptVars     map[string]string `json:"prompt_vars,omitempty"`
+
+	// Cache answers repeated requests from ~/.aiterm/cache for CacheTTL,
+	// keeping at most CacheMaxEntries answers.
+	Cache           bool   `json:"cache"`
+	CacheTTL        string `json:"cache_ttl"`
+	CacheMaxEntries int    `json:"cache_max_entries"`
+
+	// OfflineFallback answers from the built-in offline library when no
+	// endpoint can be reached.
+	OfflineFallback bool `json:"offline_fallback"`
+
+	// Examples is how many few-shot examples are sent ahead of a
+	// generation request; 0 disables them.
+	Examples int `json:"examples"`
+
+	// Prices maps model names to their token prices, used to estimate the
+	// cost of requests whose endpoint does not report one.
+	Prices map[string]Price `json:"prices,omitempty"`
+}
+
+// TransportConfig holds the network settings for API requests.
+type TransportConfig struct {
+	// Proxy is an http, https, socks5 or socks5h URL, "none" for direct
+	// connections, or empty to use HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+	Proxy string `json:"proxy,omitempty"`
+	// CABundles are PEM files of CAs trusted in addition to the system's.
+	CABundles []string `json:"ca_bundles,omitempty"`
+	// ClientCert and ClientKey are PEM files for mutual TLS.
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// PinSPKI are base64 SHA-256 hashes of public keys, as
+	// "sha256/<base64>"; the chain of the primary endpoint's host must
+	// contain one of them. Other hosts are not pinned.
+	PinSPKI []string `json:"pin_spki,omitempty"`
+}
+
+// ProxyNone disables proxies, including those from the environment.
+const ProxyNone = "none"
+
+// ContextConfig toggles the individual environment context sources.
+type ContextConfig struct {
+	CWD            bool `json:"cwd"`
+	Listing        bool `json:"listing"`
+	Git            bool `json:"git"`
+	PackageManager bool `json:"package_manager"`
+}
+
+// Any reports whether at least one source is enabled.
+func (c ContextConfig) Any() bool {
+	return c.CWD || c.Listing || c.Git || c.PackageManager
 }
 
+// Price is the cost of a model in USD per million tokens.
+type Price struct {
+	Input  float64 `json:"input_per_mtok"`
+	Output float64 `json:"output_per_mtok"`
+}
+
+// Cost returns the estimated cost in USD of the given token counts.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// PriceFor returns the configured price of model, matched case-insensitively.
+func (c *Config) PriceFor(model string) (Price, bool) {
+	for name, p := range c.Prices {
+		if strings.EqualFold(name, model) {
+			return p, true
+		}
+	}
+	return Price{}, false
+}
+
+// Endpoint is a single API endpoint with its own credentials and model.
+type Endpoint struct {
+	APIEndpoint string `json:"api_endpoint"`
+	APIToken    string `json:"api_token,omitempty"`
+	Model       string `json:"model"`
+	Provider    string `json:"provider,omitempty"`
+}
+
+// Name returns a short, token-free label for the endpoint.
+func (e Endpoint) Name() string {
+	return fmt.Sprintf("%s (%s)", e.APIEndpoint, e.Model)
+}
+
+// Defaults for the timeouts.
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultConnectTimeout = 10 * time.Second
+)
+
+// Defaults for the retry policy.
+const (
+	DefaultMaxRetries     = 3
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 10 * time.Second
+)
+
+// DefaultEndpointCooldown is how long a failed endpoint is skipped.
+const DefaultEndpointCooldown = 5 * time.Minute
+
+// DefaultToolsCacheTTL is how long installed-tool probes are reused.
+const DefaultToolsCacheTTL = 24 * time.Hour
+
+// Defaults for the response cache.
+const (
+	DefaultCacheTTL        = 24 * time.Hour
+	DefaultCacheMaxEntries = 500
+)
+
+// DefaultExamples is how many few-shot examples are sent by default.
+const DefaultExamples = 3
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
@@ -24,6 +190,27 @@ func DefaultConfig() *Config {
 		APIToken:    "",
 		Model:       "gpt-4o-mini",
 		Shell:       "auto",
+		Provider:    ProviderOpenAI,
+
+		Timeout:        DefaultTimeout.String(),
+		ConnectTimeout: DefaultConnectTimeout.String(),
+
+		MaxRetries:     DefaultMaxRetries,
+		RetryBaseDelay: DefaultRetryBaseDelay.String(),
+		RetryMaxDelay:  DefaultRetryMaxDelay.String(),
+
+		EndpointCooldown: DefaultEndpointCooldown.String(),
+
+		DetectTools:   true,
+		ToolsCacheTTL: DefaultToolsCacheTTL.String(),
+
+		Cache:           true,
+		CacheTTL:        DefaultCacheTTL.String(),
+		CacheMaxEntries: DefaultCacheMaxEntries,
+
+		OfflineFallback: true,
+
+		Examples: DefaultExamples,
 	}
 }
 
@@ -83,12 +270,13 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	// Start from the defaults so keys missing from older files keep them.
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
 
 // Save writes the configuration to disk with proper permissions.
@@ -119,8 +307,43 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// pricePrefix starts the keys that address the price table, as in
+// "price.gpt-4o-mini".
+const pricePrefix = "price."
+
+// headerPrefix starts the keys that address extra request headers, as in
+// "header.X-Team".
+const headerPrefix = "header."
+
+// promptVarPrefix starts the keys that address custom prompt variables, as
+// in "prompt_var.team".
+const promptVarPrefix = "prompt_var."
+
 // Get retrieves a configuration value by key name.
 func (c *Config) Get(key string) (string, error) {
+	if model, ok := prefixedKey(key, pricePrefix); ok {
+		p, found := c.PriceFor(model)
+		if !found {
+			return "", fmt.Errorf("no price configured for model %s", model)
+		}
+		return formatPrice(p), nil
+	}
+	if name, ok := prefixedKey(key, promptVarPrefix); ok {
+		value, found := c.PromptVars[name]
+		if !found {
+			return "", fmt.Errorf("no prompt variable %s", name)
+		}
+		return value, nil
+	}
+	if name, ok := prefixedKey(key, headerPrefix); ok {
+		for header, value := range c.Headers {
+			if strings.EqualFold(header, name) {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("no header %s", name)
+	}
+
 	switch strings.ToLower(key) {
 	case "api_endpoint":
 		return c.APIEndpoint, nil
@@ -130,6 +353,56 @@ func (c *Config) Get(key string) (string, error) {
 		return c.Model, nil
 	case "shell":
 		return c.Shell, nil
+	case "provider":
+		return c.Provider, nil
+	case "timeout":
+		return c.Timeout, nil
+	case "connect_timeout":
+		return c.ConnectTimeout, nil
+	case "max_retries":
+		return strconv.Itoa(c.MaxRetries), nil
+	case "retry_base_delay":
+		return c.RetryBaseDelay, nil
+	case "retry_max_delay":
+		return c.RetryMaxDelay, nil
+	case "endpoint_cooldown":
+		return c.EndpointCooldown, nil
+	case "prompt_template":
+		return c.PromptTemplate, nil
+	case "examples":
+		return strconv.Itoa(c.Examples), nil
+	case "offline_fallback":
+		return strconv.FormatBool(c.OfflineFallback), nil
+	case "cache":
+		return strconv.FormatBool(c.Cache), nil
+	case "cache_ttl":
+		return c.CacheTTL, nil
+	case "cache_max_entries":
+		return strconv.Itoa(c.CacheMaxEntries), nil
+	case "detect_tools":
+		return strconv.FormatBool(c.DetectTools), nil
+	case "tools":
+		return strings.Join(c.Tools, ","), nil
+	case "tools_cache_ttl":
+		return c.ToolsCacheTTL, nil
+	case "transport.proxy":
+		return c.Transport.Proxy, nil
+	case "transport.ca_bundles":
+		return strings.Join(c.Transport.CABundles, ","), nil
+	case "transport.client_cert":
+		return c.Transport.ClientCert, nil
+	case "transport.client_key":
+		return c.Transport.ClientKey, nil
+	case "transport.pin_spki":
+		return strings.Join(c.Transport.PinSPKI, ","), nil
+	case "context.cwd":
+		return strconv.FormatBool(c.Context.CWD), nil
+	case "context.listing":
+		return strconv.FormatBool(c.Context.Listing), nil
+	case "context.git":
+		return strconv.FormatBool(c.Context.Git), nil
+	case "context.package_manager":
+		return strconv.FormatBool(c.Context.PackageManager), nil
 	default:
 		return "", fmt.Errorf("unknown config key: %s", key)
 	}
@@ -137,6 +410,28 @@ func (c *Config) Get(key string) (string, error) {
 
 // Set updates a configuration value by key name and saves to disk.
 func (c *Config) Set(key, value string) error {
+	if model, ok := prefixedKey(key, pricePrefix); ok {
+		p, err := parsePrice(value)
+		if err != nil {
+			return err
+		}
+		if c.Prices == nil {
+			c.Prices = map[string]Price{}
+		}
+		c.Prices[model] = p
+		return c.Save()
+	}
+	if name, ok := prefixedKey(key, promptVarPrefix); ok {
+		if c.PromptVars == nil {
+			c.PromptVars = map[string]string{}
+		}
+		c.PromptVars[name] = value
+		return c.Save()
+	}
+	if name, ok := prefixedKey(key, headerPrefix); ok {
+		return c.setHeader(name, value)
+	}
+
 	switch strings.ToLower(key) {
 	case "api_endpoint":
 		c.APIEndpoint = value
@@ -145,27 +440,355 @@ func (c *Config) Set(key, value string) error {
 	case "model":
 		c.Model = value
 	case "shell":
+		if _, ok := shell.Lookup(value); !ok && !strings.EqualFold(value, "auto") {
+			return fmt.Errorf("unknown shell %q — must be auto or one of: %s", value, strings.Join(shell.IDs(), ", "))
+		}
 		c.Shell = value
+	case "provider":
+		if !isProvider(value) {
+			return fmt.Errorf("unknown provider %q — must be one of: %s", value, strings.Join(Providers, ", "))
+		}
+		c.Provider = strings.ToLower(value)
+	case "timeout":
+		if err := checkDuration(key, value); err != nil {
+			return err
+		}
+		c.Timeout = value
+	case "connect_timeout":
+		if err := checkDuration(key, value); err != nil {
+			return err
+		}
+		c.ConnectTimeout = value
+	case "max_retries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_retries must be a non-negative integer")
+		}
+		c.MaxRetries = n
+	case "retry_base_delay":
+		if err := checkDuration(key, value); err != nil {
+			return err
+		}
+		c.RetryBaseDelay = value
+	case "retry_max_delay":
+		if err := checkDuration(key, value); err != nil {
+			return err
+		}
+		c.RetryMaxDelay = value
+	case "endpoint_cooldown":
+		if err := checkDuration(key, value); err != nil {
+			return err
+		}
+		c.EndpointCooldown = value
+	case "prompt_template":
+		if value != "" {
+			if err := prompt.Check(value, c.PromptVars); err != nil {
+				return err
+			}
+		}
+		c.PromptTemplate = value
+	case "examples":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("examples must be a non-negative integer")
+		}
+		c.Examples = n
+	case "offline_fallback":
+		return c.setBool(key, value, &c.OfflineFallback)
+	case "cache":
+		return c.setBool(key, value, &c.Cache)
+	case "cache_ttl":
+		if err := checkDuration(key, value); err != nil {
+			return err
+		}
+		c.CacheTTL = value
+	case "cache_max_entries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("cache_max_entries must be a positive integer")
+		}
+		c.CacheMaxEntries = n
+	case "detect_tools":
+		return c.setBool(key, value, &c.DetectTools)
+	case "tools":
+		c.Tools = splitList(value)
+	case "tools_cache_ttl":
+		if err := checkDuration(key, value); err != nil {
+			return err
+		}
+		c.ToolsCacheTTL = value
+	case "transport.proxy":
+		if err := CheckProxy(value); err != nil {
+			return err
+		}
+		c.Transport.Proxy = value
+	case "transport.ca_bundles":
+		c.Transport.CABundles = splitList(value)
+	case "transport.client_cert":
+		c.Transport.ClientCert = value
+	case "transport.client_key":
+		c.Transport.ClientKey = value
+	case "transport.pin_spki":
+		pins := splitList(value)
+		for _, pin := range pins {
+			if _, err := ParsePin(pin); err != nil {
+				return err
+			}
+		}
+		c.Transport.PinSPKI = pins
+	case "context.cwd":
+		return c.setBool(key, value, &c.Context.CWD)
+	case "context.listing":
+		return c.setBool(key, value, &c.Context.Listing)
+	case "context.git":
+		return c.setBool(key, value, &c.Context.Git)
+	case "context.package_manager":
+		return c.setBool(key, value, &c.Context.PackageManager)
 	default:
 		return fmt.Errorf("unknown config key: %s", key)
 	}
 	return c.Save()
 }
 
+// setBool parses value into *field and saves.
+func (c *Config) setBool(key, value string, field *bool) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s must be true or false", key)
+	}
+	*field = b
+	return c.Save()
+}
+
+// setHeader sets an extra request header and saves; an empty value
+// removes it.
+func (c *Config) setHeader(name, value string) error {
+	if strings.ContainsAny(name, " :\r\n") {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("header values must not contain line breaks")
+	}
+	for header := range c.Headers {
+		if strings.EqualFold(header, name) {
+			delete(c.Headers, header)
+		}
+	}
+	if value != "" {
+		if c.Headers == nil {
+			c.Headers = map[string]string{}
+		}
+		c.Headers[name] = value
+	}
+	return c.Save()
+}
+
+// CheckProxy validates a transport.proxy value.
+func CheckProxy(value string) error {
+	if value == "" || strings.EqualFold(value, ProxyNone) {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid proxy %q — use a URL such as http://proxy:3128 or socks5://127.0.0.1:1080, or none", value)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+		return nil
+	}
+	return fmt.Errorf("unsupported proxy scheme %q — must be http, https, socks5 or socks5h", u.Scheme)
+}
+
+// ParsePin decodes an SPKI pin, written as "sha256/<base64>" or just the
+// base64 hash.
+func ParsePin(pin string) ([]byte, error) {
+	encoded := strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+	hash, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("invalid pin %q — must be sha256/ followed by a base64 SHA-256 hash", pin)
+	}
+	return hash, nil
+}
+
 // Validate checks that required configuration fields are present.
 func (c *Config) Validate() error {
 	if c.APIEndpoint == "" {
 		return fmt.Errorf("api_endpoint is required")
 	}
-	if c.APIToken == "" {
+	if c.Provider != "" && !isProvider(c.Provider) {
+		return fmt.Errorf("unknown provider %q — must be one of: %s", c.Provider, strings.Join(Providers, ", "))
+	}
+	// A local Ollama server does not require a token.
+	if c.APIToken == "" && !strings.EqualFold(c.Provider, ProviderOllama) {
 		return fmt.Errorf("api_token is required — run 'aiterm setup' to configure")
 	}
 	if c.Model == "" {
 		return fmt.Errorf("model is required")
 	}
+	for i, e := range c.Endpoints {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("endpoints[%d]: %w", i, err)
+		}
+	}
 	return nil
 }
 
+// CheckPromptTemplate parses and test-renders the configured
+// prompt_template, if any, so that mistakes surface before a request.
+func (c *Config) CheckPromptTemplate() error {
+	if c.PromptTemplate == "" {
+		return nil
+	}
+	if err := prompt.Check(c.PromptTemplate, c.PromptVars); err != nil {
+		return fmt.Errorf("prompt_template %s: %w", c.PromptTemplate, err)
+	}
+	return nil
+}
+
+// Validate checks that a fallback endpoint is usable.
+func (e Endpoint) Validate() error {
+	if e.APIEndpoint == "" {
+		return fmt.Errorf("api_endpoint is required")
+	}
+	if e.Provider != "" && !isProvider(e.Provider) {
+		return fmt.Errorf("unknown provider %q — must be one of: %s", e.Provider, strings.Join(Providers, ", "))
+	}
+	if e.APIToken == "" && !strings.EqualFold(e.Provider, ProviderOllama) {
+		return fmt.Errorf("api_token is required")
+	}
+	if e.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	return nil
+}
+
+// EndpointChain returns the primary endpoint followed by the configured
+// fallbacks, in the order they should be tried.
+func (c *Config) EndpointChain() []Endpoint {
+	chain := []Endpoint{{
+		APIEndpoint: c.APIEndpoint,
+		APIToken:    c.APIToken,
+		Model:       c.Model,
+		Provider:    c.Provider,
+	}}
+	return append(chain, c.Endpoints...)
+}
+
+// EndpointCooldownDuration returns how long a failed endpoint is skipped,
+// falling back to the default when unset or invalid.
+func (c *Config) EndpointCooldownDuration() time.Duration {
+	return parseDuration(c.EndpointCooldown, DefaultEndpointCooldown)
+}
+
+// ToolsCacheTTLDuration returns how long installed-tool probes are reused,
+// falling back to the default when unset or invalid.
+func (c *Config) ToolsCacheTTLDuration() time.Duration {
+	return parseDuration(c.ToolsCacheTTL, DefaultToolsCacheTTL)
+}
+
+// CacheTTLDuration returns how long cached answers are reused, falling
+// back to the default when unset or invalid.
+func (c *Config) CacheTTLDuration() time.Duration {
+	return parseDuration(c.CacheTTL, DefaultCacheTTL)
+}
+
+// TimeoutDuration returns the limit for a whole API call, falling back to
+// the default when unset or invalid.
+func (c *Config) TimeoutDuration() time.Duration {
+	return parseDuration(c.Timeout, DefaultTimeout)
+}
+
+// ConnectTimeoutDuration returns the limit for establishing a connection,
+// falling back to the default when unset or invalid.
+func (c *Config) ConnectTimeoutDuration() time.Duration {
+	return parseDuration(c.ConnectTimeout, DefaultConnectTimeout)
+}
+
+// RetryBaseDelayDuration returns the initial retry backoff, falling back to
+// the default when unset or invalid.
+func (c *Config) RetryBaseDelayDuration() time.Duration {
+	return parseDuration(c.RetryBaseDelay, DefaultRetryBaseDelay)
+}
+
+// RetryMaxDelayDuration returns the upper bound for a single retry wait,
+// falling back to the default when unset or invalid.
+func (c *Config) RetryMaxDelayDuration() time.Duration {
+	return parseDuration(c.RetryMaxDelay, DefaultRetryMaxDelay)
+}
+
+// parseDuration parses a Go duration string such as "500ms" or "10s",
+// returning fallback for empty, invalid or non-positive values.
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// checkDuration validates a duration value for the given key.
+func checkDuration(key, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%s must be a positive duration such as 500ms or 10s", key)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping blanks.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// prefixedKey returns what follows prefix in key, such as the model in
+// "price.<model>".
+func prefixedKey(key, prefix string) (name string, ok bool) {
+	if !strings.HasPrefix(strings.ToLower(key), prefix) || len(key) == len(prefix) {
+		return "", false
+	}
+	return key[len(prefix):], true
+}
+
+// parsePrice parses "<input>,<output>" in USD per million tokens.
+func parsePrice(value string) (Price, error) {
+	errFormat := fmt.Errorf("price must be \"<input>,<output>\" in USD per million tokens, such as 0.15,0.60")
+
+	in, out, found := strings.Cut(value, ",")
+	if !found {
+		return Price{}, errFormat
+	}
+	input, err := strconv.ParseFloat(strings.TrimSpace(in), 64)
+	if err != nil || input < 0 {
+		return Price{}, errFormat
+	}
+	output, err := strconv.ParseFloat(strings.TrimSpace(out), 64)
+	if err != nil || output < 0 {
+		return Price{}, errFormat
+	}
+	return Price{Input: input, Output: output}, nil
+}
+
+// formatPrice is the inverse of parsePrice.
+func formatPrice(p Price) string {
+	return strconv.FormatFloat(p.Input, 'f', -1, 64) + "," + strconv.FormatFloat(p.Output, 'f', -1, 64)
+}
+
+// isProvider reports whether name is a supported provider.
+func isProvider(name string) bool {
+	for _, p := range Providers {
+		if strings.EqualFold(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
 // MaskToken returns the API token with all but the last 4 characters masked.
 func MaskToken(token string) string {
 	if len(token) <= 4 {
@@ -178,6 +801,17 @@ func MaskToken(token string) string {
 func (c *Config) Display() string {
 	masked := *c
 	masked.APIToken = MaskToken(c.APIToken)
+	masked.Endpoints = make([]Endpoint, len(c.Endpoints))
+	for i, e := range c.Endpoints {
+		e.APIToken = MaskToken(e.APIToken)
+		masked.Endpoints[i] = e
+	}
+	if len(c.Headers) > 0 {
+		masked.Headers = make(map[string]string, len(c.Headers))
+		for name, value := range c.Headers {
+			masked.Headers[name] = MaskToken(value)
+		}
+	}
 	data, _ := json.MarshalIndent(masked, "", "  ")
 	return string(data)
 }
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -58,11 +60,193 @@ func TestGetSet(t *testing.T) {
 		t.Errorf("Get(model) failed: %v, %s", err, val)
 	}
 
+	val, err = cfg.Get("max_retries")
+	if err != nil || val != "3" {
+		t.Errorf("Get(max_retries) failed: %v, %s", err, val)
+	}
+
+	val, err = cfg.Get("examples")
+	if err != nil || val != "3" {
+		t.Errorf("Get(examples) failed: %v, %s", err, val)
+	}
+
 	// Test Get for unknown key
 	_, err = cfg.Get("nonexistent")
 	if err == nil {
 		t.Error("expected error for unknown key")
 	}
+
+	// Invalid values are rejected before anything is saved
+	if err := cfg.Set("max_retries", "-1"); err == nil {
+		t.Error("expected error for negative max_retries")
+	}
+	if err := cfg.Set("examples", "many"); err == nil {
+		t.Error("expected error for non-numeric examples")
+	}
+	if err := cfg.Set("cache_ttl", "forever"); err == nil {
+		t.Error("expected error for invalid cache_ttl")
+	}
+	if err := cfg.Set("cache_max_entries", "0"); err == nil {
+		t.Error("expected error for a zero cache_max_entries")
+	}
+	if err := cfg.Set("retry_base_delay", "soon"); err == nil {
+		t.Error("expected error for invalid retry_base_delay")
+	}
+	if err := cfg.Set("shell", "tcsh"); err == nil {
+		t.Error("expected error for unsupported shell")
+	}
+}
+
+func TestPrices(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	cfg := DefaultConfig()
+	if err := cfg.Set("price.gpt-4o-mini", "0.15, 0.60"); err != nil {
+		t.Fatalf("Set(price) failed: %v", err)
+	}
+	if val, err := cfg.Get("price.GPT-4o-mini"); err != nil || val != "0.15,0.6" {
+		t.Errorf("Get(price) = %q, %v; want 0.15,0.6", val, err)
+	}
+	if _, err := cfg.Get("price.unknown"); err == nil {
+		t.Error("expected error for a model without a price")
+	}
+
+	for _, bad := range []string{"0.15", "a,b", "-1,2"} {
+		if err := cfg.Set("price.m", bad); err == nil {
+			t.Errorf("expected error for price %q", bad)
+		}
+	}
+
+	p, _ := cfg.PriceFor("gpt-4o-mini")
+	if got := p.Cost(1000000, 500000); got != 0.45 {
+		t.Errorf("Cost = %v, want 0.45", got)
+	}
+}
+
+func TestContextKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	cfg := DefaultConfig()
+	if cfg.Context.Any() {
+		t.Fatal("environment context must be off by default")
+	}
+
+	if err := cfg.Set("context.listing", "true"); err != nil {
+		t.Fatalf("Set(context.listing) failed: %v", err)
+	}
+	if !cfg.Context.Listing || cfg.Context.CWD || !cfg.Context.Any() {
+		t.Errorf("unexpected context config: %+v", cfg.Context)
+	}
+	if val, err := cfg.Get("context.listing"); err != nil || val != "true" {
+		t.Errorf("Get(context.listing) = %q, %v", val, err)
+	}
+	if err := cfg.Set("context.git", "sometimes"); err == nil {
+		t.Error("expected error for a non-boolean value")
+	}
+}
+
+func TestTransportKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	cfg := DefaultConfig()
+	for key, value := range map[string]string{
+		"transport.proxy":      "socks5://127.0.0.1:1080",
+		"transport.ca_bundles": "/etc/ssl/a.pem, /etc/ssl/b.pem",
+		"transport.pin_spki":   "sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		"header.X-Team":        "platform",
+	} {
+		if err := cfg.Set(key, value); err != nil {
+			t.Fatalf("Set(%s) failed: %v", key, err)
+		}
+	}
+	if len(cfg.Transport.CABundles) != 2 || cfg.Transport.CABundles[1] != "/etc/ssl/b.pem" {
+		t.Errorf("unexpected CA bundles %q", cfg.Transport.CABundles)
+	}
+	if val, err := cfg.Get("header.x-team"); err != nil || val != "platform" {
+		t.Errorf("Get(header.x-team) = %q, %v", val, err)
+	}
+	if strings.Contains(cfg.Display(), "platform") {
+		t.Error("expected header values to be masked")
+	}
+
+	for key, value := range map[string]string{
+		"transport.proxy":    "ftp://proxy",
+		"transport.pin_spki": "sha256/tooshort",
+		"header.Bad Name":    "x",
+	} {
+		if err := cfg.Set(key, value); err == nil {
+			t.Errorf("Set(%s, %s): expected an error", key, value)
+		}
+	}
+
+	// An empty value removes a header.
+	if err := cfg.Set("header.X-TEAM", ""); err != nil || len(cfg.Headers) != 0 {
+		t.Errorf("expected the header to be removed, got %v, %v", cfg.Headers, err)
+	}
+}
+
+func TestPromptTemplateKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	path := filepath.Join(home, "prompt.tmpl")
+	os.WriteFile(path, []byte("{{.Default}} Team: {{.Vars.team}}"), 0600)
+
+	cfg := DefaultConfig()
+	if err := cfg.Set("prompt_template", path); err == nil {
+		t.Error("expected error while the template's variable is missing")
+	}
+	if err := cfg.Set("prompt_var.team", "infra"); err != nil {
+		t.Fatalf("Set(prompt_var.team) failed: %v", err)
+	}
+	if err := cfg.Set("prompt_template", path); err != nil {
+		t.Fatalf("Set(prompt_template) failed: %v", err)
+	}
+	if val, _ := cfg.Get("prompt_var.team"); val != "infra" {
+		t.Errorf("Get(prompt_var.team) = %q", val)
+	}
+	if err := cfg.CheckPromptTemplate(); err != nil {
+		t.Errorf("CheckPromptTemplate failed: %v", err)
+	}
+
+	os.WriteFile(path, []byte("{{.Default"), 0600)
+	if err := cfg.CheckPromptTemplate(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("expected an error naming the template, got %v", err)
+	}
+}
+
+func TestRetryDurations(t *testing.T) {
+	cfg := &Config{}
+	if cfg.RetryBaseDelayDuration() != DefaultRetryBaseDelay {
+		t.Errorf("expected default base delay, got %v", cfg.RetryBaseDelayDuration())
+	}
+
+	cfg.RetryMaxDelay = "2m"
+	if cfg.RetryMaxDelayDuration() != 2*time.Minute {
+		t.Errorf("expected 2m max delay, got %v", cfg.RetryMaxDelayDuration())
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	cfg := &Config{}
+	if cfg.TimeoutDuration() != DefaultTimeout || cfg.ConnectTimeoutDuration() != DefaultConnectTimeout {
+		t.Errorf("expected default timeouts, got %v and %v", cfg.TimeoutDuration(), cfg.ConnectTimeoutDuration())
+	}
+
+	cfg = DefaultConfig()
+	if err := cfg.Set("timeout", "2m"); err != nil || cfg.TimeoutDuration() != 2*time.Minute {
+		t.Errorf("Set(timeout) = %v, timeout %v", err, cfg.TimeoutDuration())
+	}
+	if err := cfg.Set("connect_timeout", "0s"); err == nil {
+		t.Error("expected an error for a zero connect_timeout")
+	}
 }
 
 func TestValidate(t *testing.T) {
@@ -89,6 +273,102 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidate_Provider(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIToken = "sk-test123"
+
+	cfg.Provider = "nonexistent"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error for unknown provider")
+	}
+
+	// Ollama does not require a token
+	cfg.Provider = ProviderOllama
+	cfg.APIToken = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected validation error for ollama without token: %v", err)
+	}
+
+	cfg.Provider = ProviderAnthropic
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error for anthropic without token")
+	}
+}
+
+func TestEndpointChain(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIToken = "sk-primary"
+	cfg.Endpoints = []Endpoint{
+		{APIEndpoint: "http://backup:4000/v1/chat/completions", APIToken: "sk-backup", Model: "default"},
+	}
+
+	chain := cfg.EndpointChain()
+	if len(chain) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(chain))
+	}
+	if chain[0].APIEndpoint != cfg.APIEndpoint || chain[0].APIToken != "sk-primary" {
+		t.Errorf("primary endpoint should come first, got %+v", chain[0])
+	}
+	if chain[1].Model != "default" {
+		t.Errorf("unexpected fallback endpoint: %+v", chain[1])
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	cfg.Endpoints[0].Model = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error for fallback endpoint without model")
+	}
+}
+
+func TestDisplay_MasksEndpointTokens(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Endpoints = []Endpoint{{APIEndpoint: "http://backup", APIToken: "sk-backup-secret", Model: "m"}}
+
+	display := cfg.Display()
+	if strings.Contains(display, "sk-backup-secret") {
+		t.Error("display output should not contain fallback endpoint tokens")
+	}
+	if cfg.Endpoints[0].APIToken != "sk-backup-secret" {
+		t.Error("Display must not modify the config")
+	}
+}
+
+func TestLoad_MissingKeysKeepDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatalf("ConfigDir failed: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+
+	// A config file written before the provider key existed
+	legacy := `{"api_endpoint": "http://localhost:4000/v1/chat/completions", "api_token": "sk-x", "model": "default"}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(legacy), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Provider != ProviderOpenAI {
+		t.Errorf("expected default provider %q, got %q", ProviderOpenAI, cfg.Provider)
+	}
+	if cfg.Shell != "auto" {
+		t.Errorf("expected default shell %q, got %q", "auto", cfg.Shell)
+	}
+	if cfg.Model != "default" {
+		t.Errorf("expected model from file, got %q", cfg.Model)
+	}
+}
+
 func TestSaveAndLoad(t *testing.T) {
 	// Create a temporary directory for the test
 	tmpDir := t.TempDir()
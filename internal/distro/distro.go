@@ -0,0 +1,105 @@
+// Package distro identifies Linux distributions and their package
+// managers from os-release data.
+package distro
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// osReleasePaths are read in order; the first existing file wins.
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// Info describes a Linux distribution.
+type Info struct {
+	// ID is the lower-case distribution identifier, such as "fedora".
+	ID string
+	// IDLike lists the distributions this one derives from.
+	IDLike []string
+	// VersionID is the release, such as "39" or "3.19.1".
+	VersionID string
+	// PrettyName is the human-readable name, such as "Fedora Linux 39".
+	PrettyName string
+}
+
+// packageManagers maps distribution IDs to their package manager.
+var packageManagers = map[string]string{
+	"debian": "apt", "ubuntu": "apt", "linuxmint": "apt", "pop": "apt", "raspbian": "apt", "kali": "apt",
+	"fedora": "dnf", "rhel": "dnf", "centos": "dnf", "rocky": "dnf", "almalinux": "dnf", "amzn": "dnf",
+	"arch": "pacman", "manjaro": "pacman", "endeavouros": "pacman",
+	"alpine":   "apk",
+	"opensuse": "zypper", "opensuse-leap": "zypper", "opensuse-tumbleweed": "zypper", "sles": "zypper", "suse": "zypper",
+	"gentoo": "emerge",
+	"void":   "xbps-install",
+	"nixos":  "nix-env",
+}
+
+// Detect reads the os-release file of the running system. ok is false if
+// there is none, as on macOS and Windows.
+func Detect() (Info, bool) {
+	for _, path := range osReleasePaths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		info := Parse(f)
+		f.Close()
+		return info, info.ID != ""
+	}
+	return Info{}, false
+}
+
+// Parse reads os-release formatted KEY=value lines.
+func Parse(r io.Reader) Info {
+	var info Info
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+
+		switch key {
+		case "ID":
+			info.ID = strings.ToLower(value)
+		case "ID_LIKE":
+			info.IDLike = strings.Fields(strings.ToLower(value))
+		case "VERSION_ID":
+			info.VersionID = value
+		case "PRETTY_NAME":
+			info.PrettyName = value
+		}
+	}
+	return info
+}
+
+// Name returns the most descriptive name available.
+func (i Info) Name() string {
+	if i.PrettyName != "" {
+		return i.PrettyName
+	}
+	if i.VersionID != "" {
+		return i.ID + " " + i.VersionID
+	}
+	return i.ID
+}
+
+// PackageManager returns the distribution's package manager, looking at
+// ID_LIKE for derivatives, or "" if unknown.
+func (i Info) PackageManager() string {
+	if pm, ok := packageManagers[i.ID]; ok {
+		return pm
+	}
+	for _, like := range i.IDLike {
+		if pm, ok := packageManagers[like]; ok {
+			return pm
+		}
+	}
+	if strings.HasPrefix(i.ID, "opensuse") {
+		return "zypper"
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package distro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	info := Parse(strings.NewReader(`# comment
+NAME="Rocky Linux"
+ID="rocky"
+ID_LIKE="rhel centos fedora"
+VERSION_ID="9.3"
+PRETTY_NAME="Rocky Linux 9.3 (Blue Onyx)"
+`))
+
+	if info.ID != "rocky" || info.VersionID != "9.3" || len(info.IDLike) != 3 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if info.Name() != "Rocky Linux 9.3 (Blue Onyx)" {
+		t.Errorf("Name() = %q", info.Name())
+	}
+}
+
+func TestPackageManager(t *testing.T) {
+	tests := []struct {
+		info     Info
+		expected string
+	}{
+		{Info{ID: "ubuntu"}, "apt"},
+		{Info{ID: "fedora"}, "dnf"},
+		{Info{ID: "alpine"}, "apk"},
+		{Info{ID: "arch"}, "pacman"},
+		{Info{ID: "opensuse-microos"}, "zypper"},
+		{Info{ID: "elementary", IDLike: []string{"ubuntu", "debian"}}, "apt"},
+		{Info{ID: "garuda", IDLike: []string{"arch"}}, "pacman"},
+		{Info{ID: "unknown"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.info.PackageManager(); got != tt.expected {
+			t.Errorf("PackageManager(%+v) = %q, want %q", tt.info, got, tt.expected)
+		}
+	}
+}
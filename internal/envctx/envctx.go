@@ -0,0 +1,158 @@
+// Package envctx collects opt-in facts about the local environment that
+// help the model pick the right paths and tools.
+package envctx
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"aiterm/internal/config"
+)
+
+// MaxEntries bounds the directory listing so large directories neither
+// leak much nor blow up the prompt.
+const MaxEntries = 50
+
+// lookPath finds installed programs; tests replace it.
+var lookPath = defaultLookPath
+
+var defaultLookPath = exec.LookPath
+
+// packageManagers lists the package managers probed on each OS, in order of
+// preference. Linux entries double as the default for other Unixes.
+var packageManagers = map[string][]string{
+	"darwin":  {"brew", "port"},
+	"windows": {"winget", "choco", "scoop"},
+	"linux":   {"apt", "dnf", "yum", "pacman", "zypper", "apk", "emerge", "nix-env", "brew"},
+}
+
+// Context is what was collected. Zero fields were either disabled or not
+// found.
+type Context struct {
+	Dir            string
+	Entries        []string
+	MoreEntries    int
+	GitRepo        *bool
+	PackageManager string
+}
+
+// Collect gathers the sources enabled in sources for the current working
+// directory.
+func Collect(sources config.ContextConfig) Context {
+	var c Context
+	if !sources.Any() {
+		return c
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return c
+	}
+
+	if sources.CWD {
+		c.Dir = dir
+	}
+	if sources.Listing {
+		c.Entries, c.MoreEntries = listDir(dir, MaxEntries)
+	}
+	if sources.Git {
+		inRepo := isGitRepo(dir)
+		c.GitRepo = &inRepo
+	}
+	if sources.PackageManager {
+		c.PackageManager = detectPackageManager(runtime.GOOS)
+	}
+	return c
+}
+
+// String renders the context as the prompt section sent to the model, or
+// "" if nothing was collected.
+func (c Context) String() string {
+	var lines []string
+	if c.Dir != "" {
+		lines = append(lines, "- Working directory: "+c.Dir)
+	}
+	if c.Entries != nil {
+		listing := strings.Join(c.Entries, ", ")
+		if len(c.Entries) == 0 {
+			listing = "(empty)"
+		}
+		if c.MoreEntries > 0 {
+			listing += fmt.Sprintf(", ... and %d more", c.MoreEntries)
+		}
+		lines = append(lines, "- Directory contents: "+listing)
+	}
+	if c.GitRepo != nil {
+		answer := "no"
+		if *c.GitRepo {
+			answer = "yes"
+		}
+		lines = append(lines, "- Inside a git repository: "+answer)
+	}
+	if c.PackageManager != "" {
+		lines = append(lines, "- Package manager: "+c.PackageManager)
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Environment of the user:\n" + strings.Join(lines, "\n")
+}
+
+// listDir returns up to limit sorted entry names of dir, with directories
+// marked by a trailing slash, and how many entries were left out.
+func listDir(dir string, limit int) (names []string, more int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0
+	}
+
+	names = make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > limit {
+		return names[:limit], len(names) - limit
+	}
+	return names, 0
+}
+
+// isGitRepo reports whether dir or one of its parents contains .git.
+func isGitRepo(dir string) bool {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// detectPackageManager returns the first package manager for goos that is
+// installed, or "".
+func detectPackageManager(goos string) string {
+	candidates, ok := packageManagers[goos]
+	if !ok {
+		candidates = packageManagers["linux"]
+	}
+	for _, name := range candidates {
+		if _, err := lookPath(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package envctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aiterm/internal/config"
+)
+
+func TestCollect_Disabled(t *testing.T) {
+	if got := Collect(config.ContextConfig{}).String(); got != "" {
+		t.Errorf("expected no context when every source is off, got %q", got)
+	}
+}
+
+func TestCollect_Sources(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, ".git"), 0700)
+	os.Mkdir(filepath.Join(dir, "src"), 0700)
+	os.WriteFile(filepath.Join(dir, "go.mod"), nil, 0600)
+
+	sub := filepath.Join(dir, "src")
+	wd, _ := os.Getwd()
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	lookPath = func(name string) (string, error) {
+		if name == "pacman" || name == "brew" {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() { lookPath = defaultLookPath })
+
+	// Only the enabled sources may appear.
+	got := Collect(config.ContextConfig{Git: true}).String()
+	if !strings.Contains(got, "Inside a git repository: yes") || strings.Contains(got, sub) {
+		t.Errorf("unexpected git-only context:\n%s", got)
+	}
+
+	os.Chdir(dir)
+	got = Collect(config.ContextConfig{CWD: true, Listing: true, Git: true, PackageManager: true}).String()
+	for _, want := range []string{
+		"Working directory: " + dir,
+		"Directory contents: .git/, go.mod, src/",
+		"Package manager: ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("context missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestListDir_Bounded(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < MaxEntries+5; i++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%03d", i)), nil, 0600)
+	}
+
+	names, more := listDir(dir, MaxEntries)
+	if len(names) != MaxEntries || more != 5 || names[0] != "f000" {
+		t.Errorf("got %d names (first %q) and %d more; want %d and 5", len(names), names[0], more, MaxEntries)
+	}
+
+	c := Context{Entries: names[:2], MoreEntries: more}
+	if got := c.String(); !strings.HasSuffix(got, "f000, f001, ... and 5 more") {
+		t.Errorf("unexpected listing line: %q", got)
+	}
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	lookPath = func(name string) (string, error) {
+		if name == "dnf" || name == "yum" || name == "scoop" {
+			return "/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() { lookPath = defaultLookPath })
+
+	tests := []struct {
+		goos     string
+		expected string
+	}{
+		{"linux", "dnf"},
+		{"freebsd", "dnf"},
+		{"windows", "scoop"},
+		{"darwin", ""},
+	}
+	for _, tt := range tests {
+		if got := detectPackageManager(tt.goos); got != tt.expected {
+			t.Errorf("detectPackageManager(%q) = %q, want %q", tt.goos, got, tt.expected)
+		}
+	}
+}
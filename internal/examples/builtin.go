@@ -0,0 +1,45 @@
+package examples
+
+// unixShells are the POSIX-style shells the Unix examples are written for.
+var unixShells = List{"bash", "zsh", "sh"}
+
+// Builtin is the example set shipped with aiterm.
+var Builtin = []Example{
+	// Linux and macOS, POSIX shells
+	{Prompt: "list all files larger than 100MB", Command: "find . -type f -size +100M", OS: List{"linux", "mac"}, Shells: unixShells},
+	{Prompt: "find all PDF files modified in the last 7 days", Command: "find . -type f -name '*.pdf' -mtime -7", OS: List{"linux", "mac"}, Shells: unixShells},
+	{Prompt: "count lines in all Go files", Command: "find . -name '*.go' -exec cat {} + | wc -l", OS: List{"linux", "mac"}, Shells: unixShells},
+	{Prompt: "search for TODO in python files recursively", Command: "grep -rn 'TODO' --include='*.py' .", OS: List{"linux", "mac"}, Shells: unixShells},
+	{Prompt: "show the 10 largest directories", Command: "du -sh ./* | sort -rh | head -n 10", OS: List{"linux", "mac"}, Shells: unixShells},
+	{Prompt: "compress the logs directory into a tar.gz archive", Command: "tar -czf logs.tar.gz logs", OS: List{"linux", "mac"}, Shells: unixShells},
+	{Prompt: "delete log files older than 30 days", Command: "find . -type f -name '*.log' -mtime +30 -delete", OS: List{"linux", "mac"}, Shells: unixShells},
+	{Prompt: "replace foo with bar in all txt files", Command: "find . -name '*.txt' -exec sed -i 's/foo/bar/g' {} +", OS: List{"linux"}, Shells: unixShells},
+	{Prompt: "replace foo with bar in all txt files", Command: "find . -name '*.txt' -exec sed -i '' 's/foo/bar/g' {} +", OS: List{"mac"}, Shells: unixShells},
+	{Prompt: "show which process is listening on port 8080", Command: "ss -ltnp 'sport = :8080'", OS: List{"linux"}, Shells: unixShells},
+	{Prompt: "show which process is listening on port 8080", Command: "lsof -nP -iTCP:8080 -sTCP:LISTEN", OS: List{"mac"}, Shells: unixShells},
+	{Prompt: "show memory usage", Command: "free -h", OS: List{"linux"}, Shells: unixShells},
+	{Prompt: "show memory usage", Command: "vm_stat", OS: List{"mac"}, Shells: unixShells},
+	{Prompt: "kill all processes named node", Command: "pkill -x node", OS: List{"linux", "mac"}, Shells: unixShells},
+
+	// fish
+	{Prompt: "list all files larger than 100MB", Command: "find . -type f -size +100M", OS: List{"linux", "mac"}, Shells: List{"fish"}},
+	{Prompt: "rename all jpeg files to jpg", Command: "for f in *.jpeg; mv $f (string replace -r '\\.jpeg$' .jpg $f); end", OS: List{"linux", "mac"}, Shells: List{"fish"}},
+	{Prompt: "set the EDITOR variable to vim", Command: "set -gx EDITOR vim", OS: List{"linux", "mac"}, Shells: List{"fish"}},
+
+	// Nushell
+	{Prompt: "list all files larger than 100MB", Command: "ls **/* | where type == file and size > 100mb", Shells: List{"nu"}},
+	{Prompt: "show the 5 processes using the most memory", Command: "ps | sort-by mem --reverse | first 5", Shells: List{"nu"}},
+
+	// PowerShell
+	{Prompt: "list all files larger than 100MB", Command: "Get-ChildItem -Recurse -File | Where-Object { $_.Length -gt 100MB }", Shells: List{"powershell", "pwsh"}},
+	{Prompt: "find all PDF files modified in the last 7 days", Command: "Get-ChildItem -Recurse -Filter *.pdf | Where-Object { $_.LastWriteTime -gt (Get-Date).AddDays(-7) }", Shells: List{"powershell", "pwsh"}},
+	{Prompt: "search for TODO in python files recursively", Command: "Get-ChildItem -Recurse -Filter *.py | Select-String -Pattern 'TODO'", Shells: List{"powershell", "pwsh"}},
+	{Prompt: "show which process is listening on port 8080", Command: "Get-Process -Id (Get-NetTCPConnection -LocalPort 8080 -State Listen).OwningProcess", OS: List{"win"}, Shells: List{"powershell", "pwsh"}},
+	{Prompt: "delete log files older than 30 days", Command: "Get-ChildItem -Recurse -Filter *.log | Where-Object { $_.LastWriteTime -lt (Get-Date).AddDays(-30) } | Remove-Item", Shells: List{"powershell", "pwsh"}},
+	{Prompt: "kill all processes named node", Command: "Stop-Process -Name node", Shells: List{"powershell", "pwsh"}},
+
+	// cmd.exe
+	{Prompt: "list all files larger than 100MB", Command: "forfiles /S /C \"cmd /c if @fsize GTR 104857600 echo @path\"", OS: List{"win"}, Shells: List{"cmd"}},
+	{Prompt: "search for TODO in python files recursively", Command: "findstr /S /N \"TODO\" *.py", OS: List{"win"}, Shells: List{"cmd"}},
+	{Prompt: "kill all processes named node", Command: "taskkill /IM node.exe /F", OS: List{"win"}, Shells: List{"cmd"}},
+}
@@ -0,0 +1,279 @@
+// Package examples is the few-shot library of description/command pairs
+// sent ahead of generation requests. Small models follow the expected
+// output much more reliably after seeing a few answered requests.
+package examples
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"aiterm/internal/config"
+	"aiterm/internal/shell"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dirName is the directory under the config directory that holds
+// user-defined example files.
+const dirName = "examples"
+
+// Example is a description and the command that answers it. OS and Shells
+// restrict where it applies; empty means everywhere.
+type Example struct {
+	Prompt  string `json:"prompt" yaml:"prompt"`
+	Command string `json:"command" yaml:"command"`
+	OS      List   `json:"os,omitempty" yaml:"os,omitempty"`
+	Shells  List   `json:"shells,omitempty" yaml:"shells,omitempty"`
+}
+
+// List is a list of names that may also be written as a single string,
+// so that "os: linux" and "os: [linux, mac]" both work.
+type List []string
+
+// UnmarshalJSON accepts a string or an array of strings.
+func (l *List) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = List{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("must be a string or a list of strings")
+	}
+	*l = many
+	return nil
+}
+
+// UnmarshalYAML accepts a scalar or a sequence of scalars.
+func (l *List) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = List{node.Value}
+		return nil
+	}
+	var many []string
+	if err := node.Decode(&many); err != nil {
+		return fmt.Errorf("line %d: must be a string or a list of strings", node.Line)
+	}
+	*l = many
+	return nil
+}
+
+// osNames maps the OS names accepted in example files to the names used
+// for targets.
+var osNames = map[string]string{
+	"linux":   "Linux",
+	"mac":     "macOS",
+	"macos":   "macOS",
+	"darwin":  "macOS",
+	"win":     "Windows",
+	"windows": "Windows",
+}
+
+// Matches reports whether the example applies to commands for shellType
+// on osName.
+func (e Example) Matches(osName, shellType string) bool {
+	if len(e.OS) > 0 && !containsFunc(e.OS, func(name string) bool {
+		return osNames[strings.ToLower(name)] == osName
+	}) {
+		return false
+	}
+	if len(e.Shells) > 0 {
+		target, ok := shell.Lookup(shellType)
+		if !ok {
+			return false
+		}
+		return containsFunc(e.Shells, func(name string) bool {
+			spec, ok := shell.Lookup(name)
+			return ok && spec.ID == target.ID
+		})
+	}
+	return true
+}
+
+// validate checks that the example is complete and only names known OSes
+// and shells.
+func (e Example) validate() error {
+	if strings.TrimSpace(e.Prompt) == "" || strings.TrimSpace(e.Command) == "" {
+		return fmt.Errorf("prompt and command are required")
+	}
+	for _, name := range e.OS {
+		if _, ok := osNames[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown os %q — must be linux, mac or win", name)
+		}
+	}
+	for _, name := range e.Shells {
+		if _, ok := shell.Lookup(name); !ok {
+			return fmt.Errorf("unknown shell %q — must be one of: %s", name, strings.Join(shell.IDs(), ", "))
+		}
+	}
+	return nil
+}
+
+// Dir returns the directory of user-defined example files.
+func Dir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dirName), nil
+}
+
+// Load returns the built-in examples followed by those from every .yaml,
+// .yml and .json file in Dir, in file name order. A missing directory is
+// not an error.
+func Load() ([]Example, error) {
+	library := append([]Example(nil), Builtin...)
+
+	dir, err := Dir()
+	if err != nil {
+		return library, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return library, nil
+		}
+		return nil, fmt.Errorf("failed to read examples: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		var loaded []Example
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			loaded, err = ReadFile(path)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		library = append(library, loaded...)
+	}
+	return library, nil
+}
+
+// ReadFile parses an example file: a YAML or JSON list of examples.
+func ReadFile(path string) ([]Example, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read examples: %w", err)
+	}
+
+	var loaded []Example
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &loaded)
+	} else {
+		err = yaml.Unmarshal(data, &loaded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i, e := range loaded {
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("%s: example %d: %w", path, i+1, err)
+		}
+	}
+	return loaded, nil
+}
+
+// Select returns up to n examples from library that apply to shellType on
+// osName, ranked by their lexical similarity to description. Examples that
+// share no words with it are left out. The most similar example comes
+// last, right before the actual request.
+func Select(library []Example, description, osName, shellType string, n int) []Example {
+	if n <= 0 {
+		return nil
+	}
+
+	type scored struct {
+		Example
+		score float64
+	}
+	query := words(description)
+
+	var candidates []scored
+	for _, e := range library {
+		if !e.Matches(osName, shellType) {
+			continue
+		}
+		if s := similarity(query, words(e.Prompt)); s > 0 {
+			candidates = append(candidates, scored{e, s})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	selected := make([]Example, len(candidates))
+	for i, c := range candidates {
+		selected[len(candidates)-1-i] = c.Example
+	}
+	return selected
+}
+
+// stopWords are left out of similarity, since nearly every request
+// contains some of them.
+var stopWords = map[string]bool{
+	"a": true, "all": true, "an": true, "and": true, "by": true, "for": true,
+	"from": true, "in": true, "into": true, "is": true, "it": true, "me": true,
+	"my": true, "of": true, "on": true, "or": true, "show": true, "that": true,
+	"the": true, "this": true, "to": true, "with": true,
+}
+
+// words returns the set of lowercase words in s, without stop words and a
+// trailing plural "s", so that "files" matches "file".
+func words(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		set[w] = true
+	}
+	return set
+}
+
+// similarity is the Jaccard index of two word sets: shared words divided
+// by all distinct words.
+func similarity(a, b map[string]bool) float64 {
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// containsFunc reports whether any name satisfies match.
+func containsFunc(names []string, match func(string) bool) bool {
+	for _, name := range names {
+		if match(name) {
+			return true
+		}
+	}
+	return false
+}
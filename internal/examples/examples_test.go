@@ -0,0 +1,103 @@
+package examples
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	library := []Example{
+		{Prompt: "list large files", Command: "find . -size +100M", OS: List{"linux"}},
+		{Prompt: "list files by size", Command: "ls -lS"},
+		{Prompt: "show disk usage", Command: "df -h"},
+		{Prompt: "list large files", Command: "Get-ChildItem", Shells: List{"powershell"}},
+	}
+
+	got := Select(library, "List all large FILES in /tmp", "Linux", "bash", 2)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 examples, got %v", got)
+	}
+	// The most similar example comes last.
+	if got[1].Command != "find . -size +100M" || got[0].Command != "ls -lS" {
+		t.Errorf("unexpected ranking: %v", got)
+	}
+
+	// Examples for other OSes and shells and unrelated ones are left out.
+	got = Select(library, "list large files", "macOS", "zsh", 5)
+	if len(got) != 1 || got[0].Command != "ls -lS" {
+		t.Errorf("unexpected examples for macOS: %v", got)
+	}
+	got = Select(library, "list large files", "Windows", "PowerShell", 5)
+	if len(got) != 2 || got[1].Command != "Get-ChildItem" {
+		t.Errorf("unexpected examples for PowerShell: %v", got)
+	}
+
+	if got := Select(library, "list large files", "Linux", "bash", 0); got != nil {
+		t.Errorf("expected no examples for n=0, got %v", got)
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	for i, e := range Builtin {
+		if err := e.validate(); err != nil {
+			t.Errorf("builtin example %d: %v", i, err)
+		}
+	}
+
+	for _, target := range []struct{ os, shell string }{
+		{"Linux", "bash"}, {"macOS", "zsh"}, {"Linux", "fish"}, {"Linux", "Nushell"},
+		{"Windows", "PowerShell"}, {"Windows", "PowerShell 7"}, {"Windows", "cmd.exe"},
+	} {
+		if got := Select(Builtin, "list all files larger than 100MB", target.os, target.shell, 1); len(got) != 1 {
+			t.Errorf("expected a builtin example for %s on %s", target.shell, target.os)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	library, err := Load()
+	if err != nil || len(library) != len(Builtin) {
+		t.Fatalf("expected only the builtin examples without a directory, got %d, %v", len(library), err)
+	}
+
+	dir := filepath.Join(home, ".aiterm", "examples")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.yaml", `
+- prompt: restart the web service
+  command: sudo systemctl restart nginx
+  os: linux
+  shells: [bash, zsh]
+`)
+	write("b.json", `[{"prompt": "tail the app log", "command": "kubectl logs -f deploy/app"}]`)
+	write("notes.txt", "ignored")
+
+	library, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := library[len(Builtin):]
+	if len(extra) != 2 || extra[0].Command != "sudo systemctl restart nginx" || extra[1].Command != "kubectl logs -f deploy/app" {
+		t.Fatalf("unexpected user examples: %v", extra)
+	}
+	if len(extra[0].OS) != 1 || extra[0].OS[0] != "linux" || len(extra[0].Shells) != 2 {
+		t.Errorf("expected os and shells to be parsed, got %+v", extra[0])
+	}
+
+	write("c.yml", "- prompt: broken\n  shells: tcsh\n  command: ls\n")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "c.yml: example 1: unknown shell") {
+		t.Errorf("expected an invalid file to be reported, got %v", err)
+	}
+}
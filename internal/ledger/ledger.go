@@ -0,0 +1,182 @@
+// Package ledger keeps a local record of the tokens and cost of every
+// generation, so that 'aiterm usage' can total them.
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+// Entry is the usage of one aiterm invocation.
+type Entry struct {
+	Time             time.Time `json:"time"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+
+	// Cost is the cost in USD reported by the endpoint, if any.
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+// Total sums the entries of one model.
+type Total struct {
+	Model            string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+
+	// Cost adds up reported costs and estimates from the price table.
+	Cost float64
+	// Unpriced counts entries that had neither a reported cost nor a price.
+	Unpriced int
+}
+
+// FilePath returns the full path to usage.jsonl.
+func FilePath() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "usage.jsonl"), nil
+}
+
+// Append adds e to the ledger. Each entry is a single appended line, so
+// concurrent invocations do not corrupt each other's records.
+func Append(e Entry) error {
+	path, err := FilePath()
+	if err != nil {
+		return fmt.Errorf("ledger path error: %w", err)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage: %w", err)
+	}
+
+	dirPerm, perm := os.FileMode(0700), os.FileMode(0600)
+	if runtime.GOOS == "windows" {
+		dirPerm, perm = os.ModePerm, os.ModePerm
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	return f.Close()
+}
+
+// Load returns the entries recorded at or after since. A missing ledger
+// yields no entries; malformed lines are skipped.
+func Load(since time.Time) ([]Entry, error) {
+	path, err := FilePath()
+	if err != nil {
+		return nil, fmt.Errorf("ledger path error: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// Summarize totals entries per model, sorted by model name. Entries without
+// a reported cost are priced with cfg's price table where possible.
+func Summarize(entries []Entry, cfg *config.Config) []Total {
+	byModel := map[string]*Total{}
+	for _, e := range entries {
+		t, ok := byModel[e.Model]
+		if !ok {
+			t = &Total{Model: e.Model}
+			byModel[e.Model] = t
+		}
+
+		t.Requests++
+		t.PromptTokens += e.PromptTokens
+		t.CompletionTokens += e.CompletionTokens
+		if cost, ok := EntryCost(e, cfg); ok {
+			t.Cost += cost
+		} else {
+			t.Unpriced++
+		}
+	}
+
+	totals := make([]Total, 0, len(byModel))
+	for _, t := range byModel {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Model < totals[j].Model })
+	return totals
+}
+
+// EntryCost returns the reported cost of e, or an estimate from cfg's price
+// table. ok is false if neither is available.
+func EntryCost(e Entry, cfg *config.Config) (cost float64, ok bool) {
+	if e.Cost != nil {
+		return *e.Cost, true
+	}
+	p, ok := cfg.PriceFor(e.Model)
+	if !ok {
+		return 0, false
+	}
+	return p.Cost(e.PromptTokens, e.CompletionTokens), true
+}
+
+// ParseSince turns a --since value into a point in time relative to now.
+// It accepts days and weeks ("30d", "2w"), Go durations ("12h") and dates
+// ("2024-01-31").
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q — use a period such as 30d, 2w or 12h, or a date such as 2024-01-31", value)
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,88 @@
+package ledger
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+func TestAppendLoadSummarize(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	now := time.Now()
+	cost := 0.002
+	entries := []Entry{
+		{Time: now.Add(-40 * 24 * time.Hour), Model: "gpt-4o-mini", PromptTokens: 1000, CompletionTokens: 1000},
+		{Time: now.Add(-time.Hour), Model: "gpt-4o-mini", PromptTokens: 100, CompletionTokens: 20},
+		{Time: now.Add(-time.Hour), Model: "gpt-4o-mini", PromptTokens: 200, CompletionTokens: 30, Cost: &cost},
+		{Time: now.Add(-time.Minute), Model: "llama3", PromptTokens: 50, CompletionTokens: 10},
+	}
+	for _, e := range entries {
+		if err := Append(e); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// A torn or foreign line must not hide the rest of the ledger.
+	path, _ := FilePath()
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("{not json\n")
+	f.Close()
+
+	loaded, err := Load(now.Add(-30 * 24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded) != 3 {
+		t.Fatalf("expected 3 entries within 30 days, got %d", len(loaded))
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Prices = map[string]config.Price{"gpt-4o-mini": {Input: 10, Output: 100}}
+
+	totals := Summarize(loaded, cfg)
+	if len(totals) != 2 || totals[0].Model != "gpt-4o-mini" || totals[1].Model != "llama3" {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+
+	gpt := totals[0]
+	if gpt.Requests != 2 || gpt.PromptTokens != 300 || gpt.CompletionTokens != 50 {
+		t.Errorf("unexpected gpt-4o-mini totals: %+v", gpt)
+	}
+	// 100*10/1e6 + 20*100/1e6 estimated, plus 0.002 reported.
+	if want := 0.003 + 0.002; gpt.Cost < want-1e-9 || gpt.Cost > want+1e-9 {
+		t.Errorf("gpt-4o-mini cost = %v, want %v", gpt.Cost, want)
+	}
+	if totals[1].Unpriced != 1 || totals[1].Cost != 0 {
+		t.Errorf("expected llama3 to be unpriced, got %+v", totals[1])
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"30d", now.Add(-30 * 24 * time.Hour)},
+		{"2w", now.Add(-14 * 24 * time.Hour)},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.value, now)
+		if err != nil || !got.Equal(tt.expected) {
+			t.Errorf("ParseSince(%q) = %v, %v; want %v", tt.value, got, err, tt.expected)
+		}
+	}
+
+	for _, bad := range []string{"", "soon", "-3d", "d"} {
+		if _, err := ParseSince(bad, now); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
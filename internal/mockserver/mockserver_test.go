@@ -0,0 +1,177 @@
+package mockserver
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"aiterm/internal/ai"
+	"aiterm/internal/config"
+)
+
+// start serves the rules in testdata/rules.yaml and returns a client
+// configured for the server.
+func start(t *testing.T) (*httptest.Server, *ai.Client) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	rules, err := Load(filepath.Join("testdata", "rules.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(New(rules, nil).Handler())
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.APIEndpoint = server.URL + "/v1/chat/completions"
+	cfg.APIToken = "sk-mock"
+	cfg.Model = "mock-small"
+	cfg.Shell = "bash"
+	cfg.Cache = false
+	cfg.OfflineFallback = false
+	cfg.RetryBaseDelay = "1ms"
+	cfg.RetryMaxDelay = "1ms"
+	return server, ai.NewClient(cfg)
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestServer_Generate(t *testing.T) {
+	_, client := start(t)
+	ctx := testContext(t)
+
+	if err := client.TestConnection(ctx); err != nil {
+		t.Fatalf("TestConnection failed: %v", err)
+	}
+
+	cmd, err := client.GenerateCommand(ctx, "how much disk space is left", "linux")
+	if err != nil || cmd != "df -h" {
+		t.Errorf("GenerateCommand = %q, %v", cmd, err)
+	}
+
+	var streamed []string
+	cmd, err = client.GenerateCommandStream(ctx, "free disk space", "linux", func(token string) {
+		streamed = append(streamed, token)
+	})
+	if err != nil || cmd != "df -h" || len(streamed) != 2 {
+		t.Errorf("GenerateCommandStream = %q, %v with tokens %q", cmd, err, streamed)
+	}
+	if u, ok := client.Usage(); !ok || u.PromptTokens == 0 || u.CompletionTokens == 0 {
+		t.Errorf("unexpected usage %+v, %v", u, ok)
+	}
+
+	commands, err := client.GenerateAlternatives(ctx, "disk space", "linux", 3)
+	if err != nil || strings.Join(commands, "|") != "df -h|du -sh .|ncdu /" {
+		t.Errorf("GenerateAlternatives = %q, %v", commands, err)
+	}
+
+	result, err := client.GenerateStructured(ctx, "disk space", "linux")
+	if err != nil || result.Command != "df -h" || result.Explanation != "Shows free space per filesystem." {
+		t.Errorf("GenerateStructured = %+v, %v", result, err)
+	}
+
+	// Requests no rule matches are echoed.
+	cmd, err = client.GenerateCommand(ctx, "what's up", "linux")
+	if err != nil || !strings.HasPrefix(cmd, "echo '") || !strings.Contains(cmd, `what'\''s up`) {
+		t.Errorf("GenerateCommand = %q, %v; want an echo", cmd, err)
+	}
+}
+
+func TestServer_ExplainAndFix(t *testing.T) {
+	_, client := start(t)
+	ctx := testContext(t)
+
+	exp, err := client.ExplainCommand(ctx, "ls -la | wc -l", "linux")
+	if err != nil {
+		t.Fatalf("ExplainCommand failed: %v", err)
+	}
+	if exp.Summary != "Counts the entries of a long listing." || exp.RiskLevel != ai.RiskSafe {
+		t.Errorf("unexpected explanation %+v", exp)
+	}
+	var kinds []string
+	for _, seg := range exp.Segments {
+		kinds = append(kinds, seg.Kind)
+	}
+	if strings.Join(kinds, " ") != "command flag pipe command flag" {
+		t.Errorf("unexpected segment kinds %q", kinds)
+	}
+
+	fix, err := client.FixCommand(ctx, ai.FailedCommand{Command: "gti status", ExitCode: 127, Stderr: "bash: gti: command not found"}, "linux")
+	if err != nil {
+		t.Fatalf("FixCommand failed: %v", err)
+	}
+	if fix.Command != "git status" || fix.Reason != "gti is a typo of git." {
+		t.Errorf("unexpected fix %+v", fix)
+	}
+}
+
+func TestServer_Failures(t *testing.T) {
+	_, client := start(t)
+	ctx := testContext(t)
+
+	// The first request fails with 503 and the retry succeeds.
+	if cmd, err := client.GenerateCommand(ctx, "flaky backend", "linux"); err != nil || cmd != "uptime" {
+		t.Errorf("GenerateCommand = %q, %v; want a successful retry", cmd, err)
+	}
+
+	if _, err := client.GenerateCommand(ctx, "busy backend", "linux"); err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+	if _, err := client.GenerateCommand(ctx, "broken reply", "linux"); err == nil {
+		t.Error("expected a malformed reply to fail")
+	}
+	if _, err := client.GenerateCommandStream(ctx, "broken reply", "linux", nil); err == nil {
+		t.Error("expected a malformed stream to fail")
+	}
+
+	short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.GenerateCommand(short, "slow reply", "linux"); err == nil {
+		t.Error("expected the latency to exceed the deadline")
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	server, client := start(t)
+
+	cfg := config.DefaultConfig()
+	cfg.APIEndpoint = server.URL + "/v1/chat/completions"
+	cfg.APIToken = "sk-wrong"
+	cfg.Model = "mock-small"
+	if err := ai.NewClient(cfg).TestConnection(testContext(t)); err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+
+	models, err := client.ListModels(testContext(t))
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 || models[1].ID != "mock-small" || models[1].Owner != "aiterm-mock" {
+		t.Errorf("unexpected models %+v", models)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"pattern.yaml": "rules:\n  - match: '('\n",
+		"status.yaml":  "rules:\n  - status: 200\n",
+		"latency.json": `{"latency": "soon", "rules": []}`,
+		"rules.txt":    "rules: []",
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
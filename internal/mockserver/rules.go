@@ -0,0 +1,155 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultModel is listed by /v1/models when the rules name no models.
+const DefaultModel = "mock-model"
+
+// Rules configure the replies of the mock server.
+type Rules struct {
+	// Token, if set, must be sent as "Authorization: Bearer <token>";
+	// other requests are answered with 401.
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// Models are listed by /v1/models.
+	Models []string `json:"models,omitempty" yaml:"models,omitempty"`
+	// Latency delays every reply, such as "300ms".
+	Latency string `json:"latency,omitempty" yaml:"latency,omitempty"`
+	// Default is the command for requests no rule matches. Empty echoes
+	// the request back.
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+	// Rules are tried in order; the first match answers.
+	Rules []Rule `json:"rules" yaml:"rules"`
+
+	latency time.Duration
+}
+
+// Rule answers requests whose last user message matches a pattern.
+type Rule struct {
+	// Match is a regular expression searched in the last user message.
+	// Empty matches every request.
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Command is the reply.
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Alternatives are further choices when several are requested.
+	Alternatives []string `json:"alternatives,omitempty" yaml:"alternatives,omitempty"`
+	// Explanation and RiskLevel fill structured (JSON schema) replies:
+	// the explanation of a generated command, the summary of an explain
+	// request or the reason of a fix.
+	Explanation string `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	RiskLevel   string `json:"risk_level,omitempty" yaml:"risk_level,omitempty"`
+	// Latency overrides the global latency for this rule.
+	Latency string `json:"latency,omitempty" yaml:"latency,omitempty"`
+	// Status, if set, answers with this HTTP error instead, such as 401,
+	// 429 or 503.
+	Status int `json:"status,omitempty" yaml:"status,omitempty"`
+	// RetryAfter is sent as Retry-After with 429 and 503 errors; "1s"
+	// when empty.
+	RetryAfter string `json:"retry_after,omitempty" yaml:"retry_after,omitempty"`
+	// Malformed answers with a truncated JSON body or stream chunk.
+	Malformed bool `json:"malformed,omitempty" yaml:"malformed,omitempty"`
+	// Times limits the rule to its first n matches, so that a request can
+	// fail twice and then succeed. 0 means always.
+	Times int `json:"times,omitempty" yaml:"times,omitempty"`
+
+	pattern    *regexp.Regexp
+	latency    time.Duration
+	hasWait    bool
+	retryAfter time.Duration
+}
+
+// Load reads rules from a .yaml, .yml or .json file.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	var r Rules
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &r)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &r)
+	default:
+		return nil, fmt.Errorf("%s: rules must be a .yaml, .yml or .json file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := r.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Default returns the rules used without a rules file: every request is
+// echoed back as a command.
+func Default() *Rules {
+	r := &Rules{}
+	r.compile()
+	return r
+}
+
+// compile checks the rules and prepares their patterns and latencies.
+func (r *Rules) compile() error {
+	var err error
+	if r.latency, err = parseLatency(r.Latency); err != nil {
+		return err
+	}
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.pattern, err = regexp.Compile(rule.Match); err != nil {
+			return fmt.Errorf("rule %d: invalid match: %w", i+1, err)
+		}
+		if rule.Latency != "" {
+			if rule.latency, err = parseLatency(rule.Latency); err != nil {
+				return fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			rule.hasWait = true
+		}
+		rule.retryAfter = time.Second
+		if rule.RetryAfter != "" {
+			if rule.retryAfter, err = parseLatency(rule.RetryAfter); err != nil {
+				return fmt.Errorf("rule %d: retry_after: %w", i+1, err)
+			}
+		}
+		if rule.Status != 0 && (rule.Status < 400 || rule.Status > 599 || http.StatusText(rule.Status) == "") {
+			return fmt.Errorf("rule %d: status %d is not an HTTP error", i+1, rule.Status)
+		}
+		if rule.Times < 0 {
+			return fmt.Errorf("rule %d: times must not be negative", i+1)
+		}
+	}
+	if len(r.Models) == 0 {
+		r.Models = []string{DefaultModel}
+	}
+	return nil
+}
+
+// SetLatency overrides the global latency, such as from a flag.
+func (r *Rules) SetLatency(d time.Duration) {
+	r.latency = d
+}
+
+// parseLatency parses a non-negative duration; empty is zero.
+func parseLatency(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid latency %q — use a duration such as 300ms or 2s", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,378 @@
+// Package mockserver is an OpenAI-compatible chat completions server that
+// answers from canned rules, for developing and testing aiterm without a
+// real endpoint. It can also delay replies and inject HTTP errors and
+// malformed JSON.
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server answers /v1/chat/completions and /v1/models from Rules.
+type Server struct {
+	rules *Rules
+	log   io.Writer
+
+	mu      sync.Mutex
+	matches []int
+}
+
+// New returns a server for rules that writes one line per request to log,
+// which may be nil.
+func New(rules *Rules, log io.Writer) *Server {
+	if log == nil {
+		log = io.Discard
+	}
+	return &Server{rules: rules, log: log, matches: make([]int, len(rules.Rules))}
+}
+
+// Handler returns the HTTP handler of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", s.handleChat)
+	mux.HandleFunc("/v1/models", s.handleModels)
+	return mux
+}
+
+// message is a chat message of a request.
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the part of a chat completions request the server reads.
+type chatRequest struct {
+	Model          string    `json:"model"`
+	Messages       []message `json:"messages"`
+	Stream         bool      `json:"stream"`
+	N              int       `json:"n"`
+	ResponseFormat *struct {
+		Type       string `json:"type"`
+		JSONSchema struct {
+			Name string `json:"name"`
+		} `json:"json_schema"`
+	} `json:"response_format"`
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	if !s.authorized(r) {
+		s.logf(r, "401 (bad token)")
+		writeError(w, http.StatusUnauthorized, "invalid API token")
+		return
+	}
+
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		OwnedBy string `json:"owned_by"`
+	}
+	list := struct {
+		Object string  `json:"object"`
+		Data   []model `json:"data"`
+	}{Object: "list"}
+	for _, id := range s.rules.Models {
+		list.Data = append(list.Data, model{ID: id, Object: "model", OwnedBy: "aiterm-mock"})
+	}
+	s.logf(r, "200 (%d models)", len(list.Data))
+	writeJSON(w, list)
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	if !s.authorized(r) {
+		s.logf(r, "401 (bad token)")
+		writeError(w, http.StatusUnauthorized, "invalid API token")
+		return
+	}
+
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logf(r, "400 (invalid JSON)")
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	prompt := lastUserMessage(req.Messages)
+	rule, index := s.match(prompt)
+
+	latency := s.rules.latency
+	if rule != nil && rule.hasWait {
+		latency = rule.latency
+	}
+	select {
+	case <-time.After(latency):
+	case <-r.Context().Done():
+		s.logf(r, "client gave up after %s", latency)
+		return
+	}
+
+	switch {
+	case rule != nil && rule.Status != 0:
+		s.logf(r, "rule %d: %d", index+1, rule.Status)
+		if rule.Status == http.StatusTooManyRequests || rule.Status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rule.retryAfter.Seconds()))))
+		}
+		writeError(w, rule.Status, fmt.Sprintf("mock %s", strings.ToLower(http.StatusText(rule.Status))))
+		return
+	case rule != nil && rule.Malformed:
+		s.logf(r, "rule %d: malformed reply", index+1)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "data: {\"choices\": [{\"delta\": {\"content\": \"ls\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices": [{"message": {"content": "ls`)
+		return
+	}
+
+	choices := s.choices(rule, prompt, req.N)
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == "json_schema" {
+		for i, c := range choices {
+			choices[i] = structured(req.ResponseFormat.JSONSchema.Name, rule, prompt, c)
+		}
+	}
+	if rule != nil {
+		s.logf(r, "rule %d: %q", index+1, choices[0])
+	} else {
+		s.logf(r, "default: %q", choices[0])
+	}
+
+	usage := map[string]int{
+		"prompt_tokens":     countTokens(req.Messages),
+		"completion_tokens": len(strings.Fields(strings.Join(choices, " "))),
+	}
+	usage["total_tokens"] = usage["prompt_tokens"] + usage["completion_tokens"]
+
+	if req.Stream {
+		writeStream(w, req.Model, choices[0], usage)
+		return
+	}
+	writeCompletion(w, req.Model, choices, usage)
+}
+
+// authorized reports whether r carries the configured token, if any.
+func (s *Server) authorized(r *http.Request) bool {
+	return s.rules.Token == "" || r.Header.Get("Authorization") == "Bearer "+s.rules.Token
+}
+
+// match returns the first rule that matches prompt and still applies,
+// counting the match, or nil when none does.
+func (s *Server) match(prompt string) (*Rule, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.rules.Rules {
+		rule := &s.rules.Rules[i]
+		if rule.Times > 0 && s.matches[i] >= rule.Times {
+			continue
+		}
+		if rule.pattern.MatchString(prompt) {
+			s.matches[i]++
+			return rule, i
+		}
+	}
+	return nil, -1
+}
+
+// choices returns up to n replies for prompt from rule, or the default.
+func (s *Server) choices(rule *Rule, prompt string, n int) []string {
+	var choices []string
+	switch {
+	case rule != nil && rule.Command != "":
+		choices = append([]string{rule.Command}, rule.Alternatives...)
+	case s.rules.Default != "":
+		choices = []string{s.rules.Default}
+	default:
+		choices = []string{echo(prompt)}
+	}
+	if n < 1 {
+		n = 1
+	}
+	if len(choices) > n {
+		choices = choices[:n]
+	}
+	return choices
+}
+
+// structured wraps command in the JSON object the schema of a
+// schema-constrained request asks for: an explanation of the command in
+// prompt for "command_explanation", a corrected command for "command_fix"
+// and a generated command otherwise.
+func structured(schema string, rule *Rule, prompt, command string) string {
+	explanation, risk := "Canned reply from the mock server.", "low"
+	if rule != nil && rule.Explanation != "" {
+		explanation = rule.Explanation
+	}
+	if rule != nil && rule.RiskLevel != "" {
+		risk = rule.RiskLevel
+	}
+
+	var result map[string]interface{}
+	switch schema {
+	case "command_explanation":
+		result = map[string]interface{}{
+			"summary":      explanation,
+			"segments":     segments(explained(prompt)),
+			"side_effects": []string{},
+			"risk_level":   risk,
+		}
+	case "command_fix":
+		result = map[string]interface{}{
+			"command": command,
+			"reason":  explanation,
+		}
+	default:
+		result = map[string]interface{}{
+			"command":       command,
+			"explanation":   explanation,
+			"risk_level":    risk,
+			"requires_sudo": strings.HasPrefix(command, "sudo "),
+			"assumptions":   []string{},
+		}
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// explained returns the command an explain prompt asks about: the text
+// after its first line, or the whole prompt if it has only one.
+func explained(prompt string) string {
+	if _, rest, ok := strings.Cut(prompt, "\n"); ok {
+		return strings.TrimSpace(rest)
+	}
+	return strings.TrimSpace(prompt)
+}
+
+// segments splits command at whitespace into explanation segments with a
+// rough kind for each piece.
+func segments(command string) []map[string]string {
+	list := []map[string]string{}
+	program := true
+	for _, field := range strings.Fields(command) {
+		kind := "argument"
+		switch {
+		case field == "|":
+			kind, program = "pipe", true
+		case field == "&&" || field == "||" || field == ";":
+			kind, program = "operator", true
+		case strings.HasPrefix(field, ">") || strings.HasPrefix(field, "<"):
+			kind = "redirection"
+		case program:
+			kind, program = "command", false
+		case strings.HasPrefix(field, "-"):
+			kind = "flag"
+		}
+		list = append(list, map[string]string{"text": field, "kind": kind, "explanation": "Canned " + kind + " from the mock server."})
+	}
+	return list
+}
+
+// writeCompletion writes a non-streamed chat completion.
+func writeCompletion(w http.ResponseWriter, model string, choices []string, usage map[string]int) {
+	type choice struct {
+		Index   int     `json:"index"`
+		Message message `json:"message"`
+		Finish  string  `json:"finish_reason"`
+	}
+	resp := struct {
+		ID      string         `json:"id"`
+		Object  string         `json:"object"`
+		Model   string         `json:"model"`
+		Choices []choice       `json:"choices"`
+		Usage   map[string]int `json:"usage"`
+	}{ID: "chatcmpl-mock", Object: "chat.completion", Model: model, Usage: usage}
+	for i, c := range choices {
+		resp.Choices = append(resp.Choices, choice{Index: i, Message: message{Role: "assistant", Content: c}, Finish: "stop"})
+	}
+	writeJSON(w, resp)
+}
+
+// writeStream writes reply as server-sent events, a word at a time,
+// followed by a usage chunk and the end marker.
+func writeStream(w http.ResponseWriter, model, reply string, usage map[string]int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk interface{}) {
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for _, word := range strings.SplitAfter(reply, " ") {
+		send(map[string]interface{}{
+			"id":      "chatcmpl-mock",
+			"object":  "chat.completion.chunk",
+			"model":   model,
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": map[string]string{"content": word}}},
+		})
+	}
+	send(map[string]interface{}{
+		"id":      "chatcmpl-mock",
+		"object":  "chat.completion.chunk",
+		"model":   model,
+		"choices": []interface{}{},
+		"usage":   usage,
+	})
+	io.WriteString(w, "data: [DONE]\n\n")
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the OpenAI format.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": msg, "type": "mock_error"},
+	})
+}
+
+// logf writes a line about the request.
+func (s *Server) logf(r *http.Request, format string, args ...interface{}) {
+	fmt.Fprintf(s.log, "%s %s %s → %s\n", time.Now().Format("15:04:05"), r.Method, r.URL.Path, fmt.Sprintf(format, args...))
+}
+
+// lastUserMessage returns the content of the last user message.
+func lastUserMessage(messages []message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// countTokens roughly counts the tokens of messages as words.
+func countTokens(messages []message) int {
+	n := 0
+	for _, m := range messages {
+		n += len(strings.Fields(m.Content))
+	}
+	return n
+}
+
+// echo returns a command that prints the first line of prompt.
+func echo(prompt string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	return "echo '" + strings.ReplaceAll(line, "'", `'\''`) + "'"
+}
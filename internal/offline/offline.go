@@ -0,0 +1,235 @@
+// Package offline suggests commands for common tasks from a library built
+// into the binary, for when no model can be reached.
+package offline
+
+import (
+	_ "embed"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"aiterm/internal/shell"
+)
+
+//go:embed patterns.json
+var patternsJSON []byte
+
+// numberPlaceholder is replaced by the first number in the description,
+// such as the port in "kill whatever runs on port 3000".
+const numberPlaceholder = "{n}"
+
+// minCoverage is the share of the description's words a task must match
+// to be suggested at all.
+const minCoverage = 0.5
+
+// Pattern is a common task with its command per OS and shell.
+type Pattern struct {
+	Task     string   `json:"task"`
+	Keywords []string `json:"keywords"`
+	// Required lists groups of words, naming the object of the task, of
+	// which the description must contain one each. Generic verbs such as
+	// "remove" or "kill" alone never select a task.
+	Required [][]string `json:"required"`
+	// Excluded are words that rule the task out, such as a unit of size
+	// other than the one its command uses.
+	Excluded []string  `json:"excluded,omitempty"`
+	Default  string    `json:"default,omitempty"`
+	Variants []Variant `json:"variants"`
+}
+
+// Variant is the command for a task on some OSes and shells; empty lists
+// match any.
+type Variant struct {
+	OS      []string `json:"os,omitempty"`
+	Shells  []string `json:"shells,omitempty"`
+	Command string   `json:"command"`
+}
+
+// Suggestion is a command from the library.
+type Suggestion struct {
+	Task    string
+	Command string
+}
+
+// Patterns returns the built-in library. It panics if the embedded file
+// is malformed, which the tests rule out.
+func Patterns() []Pattern {
+	var patterns []Pattern
+	if err := json.Unmarshal(patternsJSON, &patterns); err != nil {
+		panic("offline: invalid patterns.json: " + err.Error())
+	}
+	return patterns
+}
+
+// Suggest returns up to n suggestions for description for shellType on
+// osName, best first. Tasks match by keyword, tolerating plurals and
+// single-letter typos; tasks matching too little of the description are
+// left out.
+func Suggest(description, osName, shellType string, n int) []Suggestion {
+	query := words(description)
+	if len(query) == 0 || n <= 0 {
+		return nil
+	}
+
+	type scored struct {
+		Suggestion
+		matched  int
+		keywords int
+	}
+	var candidates []scored
+	for _, p := range Patterns() {
+		v, ok := p.variant(osName, shellType)
+		if !ok {
+			continue
+		}
+		matched, excluded := 0, false
+		for _, w := range query {
+			if matchesAny(w, p.Keywords) {
+				matched++
+			}
+			excluded = excluded || matchesAny(w, p.Excluded)
+		}
+		if matched == 0 || excluded || float64(matched)/float64(len(query)) < minCoverage || !p.hasRequired(query) {
+			continue
+		}
+		number := firstNumber(description, p.Default)
+		candidates = append(candidates, scored{
+			Suggestion: Suggestion{
+				Task:    strings.ReplaceAll(p.Task, numberPlaceholder, number),
+				Command: strings.ReplaceAll(v.Command, numberPlaceholder, number),
+			},
+			matched:  matched,
+			keywords: len(p.Keywords),
+		})
+	}
+
+	// More matched words first; among equals, the more specific task.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].matched != candidates[j].matched {
+			return candidates[i].matched > candidates[j].matched
+		}
+		return candidates[i].keywords < candidates[j].keywords
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	suggestions := make([]Suggestion, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = c.Suggestion
+	}
+	return suggestions
+}
+
+// hasRequired reports whether query has a word of each required group.
+func (p Pattern) hasRequired(query []string) bool {
+	for _, group := range p.Required {
+		found := false
+		for _, w := range query {
+			if matchesAny(w, group) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// variant returns the first variant of p for shellType on osName.
+func (p Pattern) variant(osName, shellType string) (Variant, bool) {
+	target, known := shell.Lookup(shellType)
+	for _, v := range p.Variants {
+		if len(v.OS) > 0 && !containsFold(v.OS, osName) {
+			continue
+		}
+		if len(v.Shells) > 0 && (!known || !containsFold(v.Shells, target.ID)) {
+			continue
+		}
+		return v, true
+	}
+	return Variant{}, false
+}
+
+// stopWords carry no meaning for matching.
+var stopWords = map[string]bool{
+	"a": true, "all": true, "an": true, "and": true, "are": true, "by": true,
+	"can": true, "do": true, "for": true, "from": true, "i": true, "in": true,
+	"into": true, "is": true, "it": true, "me": true, "of": true, "on": true,
+	"or": true, "please": true, "show": true, "than": true, "that": true,
+	"the": true, "this": true, "to": true, "what": true, "with": true,
+}
+
+// words returns the meaningful lowercase words of s, without numbers. A
+// number written together with its unit, such as "500MB", leaves the unit.
+func words(s string) []string {
+	var list []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		w = strings.TrimLeftFunc(w, unicode.IsDigit)
+		if w == "" || stopWords[w] {
+			continue
+		}
+		list = append(list, w)
+	}
+	return list
+}
+
+// matchesAny reports whether w matches one of the keywords: exactly, as a
+// plural, or, for longer words, with a single typo.
+func matchesAny(w string, keywords []string) bool {
+	for _, k := range keywords {
+		switch {
+		case w == k, w == k+"s", w+"s" == k, w == k+"es":
+			return true
+		case len(w) >= 5 && len(k) >= 5 && withinOneEdit(w, k):
+			return true
+		}
+	}
+	return false
+}
+
+// withinOneEdit reports whether a and b differ by at most one inserted,
+// deleted or replaced letter.
+func withinOneEdit(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(a) && a[i] == b[i] {
+		i++
+	}
+	if len(a) == len(b) {
+		return a[i+min(1, len(a)-i):] == b[i+min(1, len(b)-i):]
+	}
+	return a[i:] == b[i+1:]
+}
+
+// numberPattern finds numbers in a description.
+var numberPattern = regexp.MustCompile(`\d+`)
+
+// firstNumber returns the first number in s, or fallback.
+func firstNumber(s, fallback string) string {
+	if n := numberPattern.FindString(s); n != "" {
+		return n
+	}
+	return fallback
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package offline
+
+import (
+	"strings"
+	"testing"
+
+	"aiterm/internal/shell"
+)
+
+func TestPatterns(t *testing.T) {
+	for _, p := range Patterns() {
+		if p.Task == "" || len(p.Keywords) == 0 || len(p.Required) == 0 || len(p.Variants) == 0 {
+			t.Errorf("incomplete pattern %+v", p)
+		}
+		usesNumber := strings.Contains(p.Task, numberPlaceholder)
+		for _, v := range p.Variants {
+			if v.Command == "" {
+				t.Errorf("%s: variant without a command", p.Task)
+			}
+			usesNumber = usesNumber || strings.Contains(v.Command, numberPlaceholder)
+			for _, name := range v.Shells {
+				if _, ok := shell.Lookup(name); !ok {
+					t.Errorf("%s: unknown shell %q", p.Task, name)
+				}
+			}
+			for _, name := range v.OS {
+				if name != "Linux" && name != "macOS" && name != "Windows" {
+					t.Errorf("%s: unknown OS %q", p.Task, name)
+				}
+			}
+		}
+		if usesNumber && p.Default == "" {
+			t.Errorf("%s: uses %s without a default", p.Task, numberPlaceholder)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		description string
+		os, shell   string
+		command     string
+	}{
+		{"find all files larger than 500MB", "Linux", "bash", "find . -type f -size +500M"},
+		{"find big files", "macOS", "zsh", "find . -type f -size +100M"},
+		{"find files larger than 1 GB", "Linux", "bash", "find . -type f -size +1G"},
+		{"find files over 2GB", "Windows", "PowerShell", "Get-ChildItem -Recurse -File | Where-Object { $_.Length -gt 2GB }"},
+		{"kill whatever is running on port 3000", "Linux", "bash", "fuser -k 3000/tcp"},
+		{"kill process on port 3000", "macOS", "fish", "kill (lsof -t -iTCP:3000 -sTCP:LISTEN)"},
+		{"kill the process on port 5173", "Windows", "PowerShell", "Stop-Process -Id (Get-NetTCPConnection -LocalPort 5173 -State Listen).OwningProcess"},
+		{"how much disk space is free", "Linux", "bash", "df -h"},
+		{"show dsik space", "Linux", "bash", "df -h"},
+		{"untar archive", "Linux", "bash", "tar -xzf archive.tar.gz"},
+		{"extract the zip", "Windows", "PowerShell", "Expand-Archive -Path archive.zip -DestinationPath ."},
+		{"largest folders", "Linux", "fish", "du -sh ./* | sort -rh | head -n 10"},
+		{"delete files older than 14 days", "Linux", "bash", "find . -type f -mtime +14 -delete"},
+		{"undo my last commit", "Windows", "cmd.exe", "git reset --soft HEAD~1"},
+	}
+
+	for _, tt := range tests {
+		got := Suggest(tt.description, tt.os, tt.shell, 1)
+		if len(got) != 1 {
+			t.Errorf("Suggest(%q, %s, %s): no suggestion", tt.description, tt.os, tt.shell)
+			continue
+		}
+		if got[0].Command != tt.command {
+			t.Errorf("Suggest(%q, %s, %s) = %q (%s), want %q", tt.description, tt.os, tt.shell, got[0].Command, got[0].Task, tt.command)
+		}
+	}
+}
+
+func TestSuggest_NoMatch(t *testing.T) {
+	for _, description := range []string{
+		"", "write a haiku about the sea", "translate hello into french and german",
+		// Generic verbs alone must not select destructive tasks.
+		"remove old logs", "delete the temp files", "kill the build",
+		"cleanup build output", "remove old docker images",
+	} {
+		if got := Suggest(description, "Linux", "bash", 3); len(got) != 0 {
+			t.Errorf("Suggest(%q) = %v, want none", description, got)
+		}
+	}
+
+	// A size in another unit must not get the MB command.
+	for _, description := range []string{"find files larger than 1 GB", "find files bigger than 500 KB"} {
+		for _, s := range Suggest(description, "Linux", "bash", 3) {
+			if strings.HasSuffix(s.Command, "M") {
+				t.Errorf("Suggest(%q) offered %q", description, s.Command)
+			}
+		}
+	}
+
+	// Tasks without a variant for the shell are not suggested.
+	if got := Suggest("make script executable", "Windows", "PowerShell", 3); len(got) != 0 {
+		t.Errorf("expected no suggestion for chmod on Windows, got %v", got)
+	}
+}
+
+func TestSuggest_Ranked(t *testing.T) {
+	got := Suggest("kill process on port 8080", "Linux", "bash", 3)
+	if len(got) < 2 || got[0].Task != "Kill the process listening on port 8080" {
+		t.Fatalf("unexpected suggestions: %v", got)
+	}
+}
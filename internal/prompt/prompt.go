@@ -0,0 +1,88 @@
+// Package prompt renders user-defined system prompt templates.
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Data is what a prompt template can refer to.
+type Data struct {
+	// OS is the target OS name, such as "Linux".
+	OS string
+	// Shell is the target shell's display name, such as "PowerShell 7".
+	Shell string
+	// Distro is the Linux distribution, such as "Fedora Linux 39", or "".
+	Distro string
+	// PackageManager is the distribution's package manager, or "".
+	PackageManager string
+	// Cwd is the current working directory.
+	Cwd string
+	// Vars holds the custom variables from the prompt_vars config key.
+	Vars map[string]string
+	// Default is the built-in system prompt, so templates can extend it.
+	Default string
+}
+
+// ExpandPath resolves a leading "~/" to the user's home directory.
+func ExpandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// Parse reads and parses the template file at path. Referring to a custom
+// variable that is not configured is an error when rendering.
+func Parse(path string) (*template.Template, error) {
+	data, err := os.ReadFile(ExpandPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+
+	t, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return t, nil
+}
+
+// Render executes the template file at path with data.
+func Render(path string, data Data) (string, error) {
+	t, err := Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+
+	rendered := strings.TrimSpace(b.String())
+	if rendered == "" {
+		return "", fmt.Errorf("prompt template %s renders to an empty prompt", path)
+	}
+	return rendered, nil
+}
+
+// Check parses the template file at path and renders it with placeholder
+// values, so that unknown fields and missing custom variables are reported
+// before any request is made.
+func Check(path string, vars map[string]string) error {
+	_, err := Render(path, Data{
+		OS:             "Linux",
+		Shell:          "bash",
+		Distro:         "Debian GNU/Linux 12",
+		PackageManager: "apt",
+		Cwd:            "/home/user",
+		Vars:           vars,
+		Default:        "You are a shell command generator.",
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemplate writes a template file and returns its path.
+func writeTemplate(t *testing.T, body string) string {
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRender(t *testing.T) {
+	path := writeTemplate(t, `{{.Default}}
+Target: {{.Shell}} on {{.OS}}{{if .Distro}} ({{.Distro}}, {{.PackageManager}}){{end}} in {{.Cwd}}.
+Team: {{.Vars.team}}. Always use long flags.
+`)
+
+	got, err := Render(path, Data{
+		OS: "Linux", Shell: "fish", Distro: "Alpine", PackageManager: "apk", Cwd: "/srv",
+		Vars:    map[string]string{"team": "infra"},
+		Default: "You are a shell command generator.",
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "You are a shell command generator.\nTarget: fish on Linux (Alpine, apk) in /srv.\nTeam: infra. Always use long flags."
+	if got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		vars    map[string]string
+		wantErr string
+	}{
+		{"valid", "{{.Default}} Team {{.Vars.team}}.", map[string]string{"team": "x"}, ""},
+		{"syntax error", "{{.Default", nil, "invalid prompt template"},
+		{"unknown field", "{{.Hostname}}", nil, "can't evaluate field Hostname"},
+		{"missing variable", "{{.Vars.team}}", nil, `map has no entry for key "team"`},
+		{"empty", "{{/* nothing */}}\n", nil, "empty prompt"},
+	}
+	for _, tt := range tests {
+		err := Check(writeTemplate(t, tt.body), tt.vars)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if err := Check(filepath.Join(t.TempDir(), "missing.tmpl"), nil); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
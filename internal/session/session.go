@@ -0,0 +1,78 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"aiterm/internal/ai"
+	"aiterm/internal/config"
+)
+
+// ErrNoSession is returned by Load when no exchange has been saved yet.
+var ErrNoSession = errors.New("no previous command to refine — run 'aiterm \"...\"' first")
+
+// FilePath returns the full path to session.json.
+func FilePath() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session.json"), nil
+}
+
+// Load reads the last saved exchange.
+func Load() (*ai.Exchange, error) {
+	path, err := FilePath()
+	if err != nil {
+		return nil, fmt.Errorf("session path error: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoSession
+		}
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var ex ai.Exchange
+	if err := json.Unmarshal(data, &ex); err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+	if len(ex.Messages) == 0 {
+		return nil, ErrNoSession
+	}
+
+	return &ex, nil
+}
+
+// Save stores ex as the last exchange, readable only by the owner since it
+// contains the user's prompts.
+func Save(ex *ai.Exchange) error {
+	path, err := FilePath()
+	if err != nil {
+		return err
+	}
+
+	dirPerm, filePerm := os.FileMode(0700), os.FileMode(0600)
+	if runtime.GOOS == "windows" {
+		dirPerm, filePerm = os.ModePerm, os.ModePerm
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, filePerm); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"aiterm/internal/ai"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	if _, err := Load(); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("expected ErrNoSession before any save, got %v", err)
+	}
+
+	ex := &ai.Exchange{
+		Prompt:  "list big files",
+		Command: "du -ah . | sort -rh | head",
+		Target:  "linux",
+		Messages: []ai.Message{
+			{Role: "system", Content: "You are a shell command generator."},
+			{Role: "user", Content: "Generate a single shell command for: list big files"},
+			{Role: "assistant", Content: "du -ah . | sort -rh | head"},
+		},
+	}
+	if err := Save(ex); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Command != ex.Command || loaded.Target != ex.Target || len(loaded.Messages) != 3 {
+		t.Errorf("loaded session mismatch: %+v", loaded)
+	}
+}
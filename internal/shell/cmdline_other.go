@@ -0,0 +1,9 @@
+//go:build !windows
+
+package shell
+
+import "os/exec"
+
+// setCmdLine does nothing: only Windows passes raw command lines, and
+// cmd.exe does not run elsewhere.
+func setCmdLine(c *exec.Cmd, line string) {}
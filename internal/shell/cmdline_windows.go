@@ -0,0 +1,14 @@
+//go:build windows
+
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCmdLine makes c start with the raw command line instead of one built
+// from its arguments.
+func setCmdLine(c *exec.Cmd, line string) {
+	c.SysProcAttr = &syscall.SysProcAttr{CmdLine: line}
+}
@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Streams holds the standard streams attached to an executed command.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Args returns the program and arguments used to run command through the
+// given shell. The shell may be a display name ("PowerShell 7"), a bare name
+// ("zsh") or a path ("/bin/bash"); paths are kept as given.
+func Args(shellName, command string) (string, []string) {
+	prog := shellName
+	spec, ok := Lookup(shellName)
+	if ok && !strings.ContainsAny(shellName, `/\`) {
+		prog = spec.Program
+	}
+
+	switch {
+	case !ok && (shellName == "" || strings.EqualFold(shellName, "auto")):
+		return "sh", []string{"-c", command}
+	case spec.ID == PowerShell, spec.ID == Pwsh:
+		return prog, []string{"-NoProfile", "-Command", command}
+	case spec.ID == Cmd:
+		return prog, []string{"/S", "/C", command}
+	default:
+		return prog, []string{"-c", command}
+	}
+}
+
+// Run executes command through the given shell, streaming its output to the
+// provided writers, and returns the command's exit code. A non-nil error is
+// only returned when the command could not be started at all.
+func Run(ctx context.Context, shellName, command string, streams Streams) (int, error) {
+	prog, args := Args(shellName, command)
+
+	c := exec.CommandContext(ctx, prog, args...)
+	if spec, ok := Lookup(shellName); ok && spec.ID == Cmd {
+		setCmdLine(c, cmdLine(prog, command))
+	}
+	c.Stdin = streams.Stdin
+	c.Stdout = streams.Stdout
+	c.Stderr = streams.Stderr
+
+	err := c.Run()
+	if err == nil {
+		return 0, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return -1, fmt.Errorf("failed to run %s: %w", prog, err)
+}
+
+// cmdLine returns the raw command line that runs command through cmd.exe.
+// cmd.exe does not follow the quoting rules Go uses for arguments (\"), so
+// the command is passed verbatim: with /S, cmd.exe strips the outer quotes
+// and runs everything between them as typed.
+func cmdLine(prog, command string) string {
+	if strings.ContainsAny(prog, " \t") {
+		prog = `"` + prog + `"`
+	}
+	return prog + ` /S /C "` + command + `"`
+}
@@ -0,0 +1,122 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestArgs(t *testing.T) {
+	tests := []struct {
+		shell        string
+		expectedProg string
+		expectedFlag string
+	}{
+		{"bash", "bash", "-c"},
+		{"zsh", "zsh", "-c"},
+		{"/bin/bash", "/bin/bash", "-c"},
+		{"PowerShell", "powershell", "-Command"},
+		{"pwsh", "pwsh", "-Command"},
+		{"cmd.exe", "cmd", "/C"},
+		{"auto", "sh", "-c"},
+		{"PowerShell 7", "pwsh", "-Command"},
+		{"fish", "fish", "-c"},
+		{"/opt/homebrew/bin/fish", "/opt/homebrew/bin/fish", "-c"},
+		{"Nushell", "nu", "-c"},
+		{"POSIX sh", "sh", "-c"},
+	}
+
+	for _, tt := range tests {
+		prog, args := Args(tt.shell, "echo hi")
+		if prog != tt.expectedProg {
+			t.Errorf("Args(%q) prog = %q, want %q", tt.shell, prog, tt.expectedProg)
+		}
+		if len(args) < 2 || args[len(args)-2] != tt.expectedFlag || args[len(args)-1] != "echo hi" {
+			t.Errorf("Args(%q) args = %v, want [... %s \"echo hi\"]", tt.shell, args, tt.expectedFlag)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	var stdout bytes.Buffer
+	code, err := Run(context.Background(), "sh", "echo hello; exit 3", Streams{Stdout: &stdout})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+	if strings.TrimSpace(stdout.String()) != "hello" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "hello")
+	}
+}
+
+func TestRun_MissingShell(t *testing.T) {
+	_, err := Run(context.Background(), "definitely-not-a-shell", "true", Streams{})
+	if err == nil {
+		t.Fatal("expected error for missing shell")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"fish", Fish},
+		{"/usr/bin/zsh", Zsh},
+		{"-zsh", Zsh},
+		{"nushell", Nushell},
+		{"Nushell", Nushell},
+		{"dash", POSIX},
+		{`C:\Windows\System32\cmd.exe`, Cmd},
+		{"PowerShell", PowerShell},
+		{"PowerShell 7", Pwsh},
+		{"pwsh.exe", Pwsh},
+	}
+	for _, tt := range tests {
+		spec, ok := Lookup(tt.name)
+		if !ok || spec.ID != tt.expected {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", tt.name, spec.ID, ok, tt.expected)
+		}
+	}
+
+	for _, unknown := range []string{"", "auto", "tcsh", "go"} {
+		if _, ok := Lookup(unknown); ok {
+			t.Errorf("Lookup(%q) should not match", unknown)
+		}
+	}
+}
+
+func TestSpec_SupportsOS(t *testing.T) {
+	cmd, _ := Lookup(Cmd)
+	pwsh, _ := Lookup(Pwsh)
+	fish, _ := Lookup(Fish)
+
+	if cmd.SupportsOS("Linux") || !cmd.SupportsOS("Windows") {
+		t.Error("cmd.exe only exists on Windows")
+	}
+	if !pwsh.SupportsOS("Linux") || !pwsh.SupportsOS("Windows") {
+		t.Error("PowerShell 7 is cross-platform")
+	}
+	if fish.SupportsOS("Windows") || !fish.POSIXLike() || pwsh.POSIXLike() {
+		t.Error("unexpected fish/pwsh capabilities")
+	}
+}
+
+func TestCmdLine(t *testing.T) {
+	command := `findstr /C:"hello world" "my notes.txt" && echo "done"`
+	want := `cmd /S /C "` + command + `"`
+	if got := cmdLine("cmd", command); got != want {
+		t.Errorf("cmdLine = %s, want %s", got, want)
+	}
+	if got := cmdLine(`C:\Program Files\cmd.exe`, "ver"); got != `"C:\Program Files\cmd.exe" /S /C "ver"` {
+		t.Errorf("unexpected command line %s", got)
+	}
+}
@@ -0,0 +1,170 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Shell identifiers accepted by the shell config key and --shell.
+const (
+	Bash       = "bash"
+	Zsh        = "zsh"
+	Fish       = "fish"
+	Nushell    = "nu"
+	POSIX      = "sh"
+	Cmd        = "cmd"
+	PowerShell = "powershell"
+	Pwsh       = "pwsh"
+)
+
+// Spec describes a supported shell.
+type Spec struct {
+	// ID is the canonical identifier, such as "pwsh".
+	ID string
+	// Name is the display name used in prompts, such as "PowerShell 7".
+	Name string
+	// Program is the executable commands run through.
+	Program string
+	// Guidance tells the model about the shell's syntax.
+	Guidance string
+	// OSes lists the target OS names the shell exists on.
+	OSes []string
+
+	aliases []string
+}
+
+// POSIXLike reports whether commands in this shell start with a program
+// looked up on PATH, as in bash or fish, rather than a built-in cmdlet.
+func (s Spec) POSIXLike() bool {
+	switch s.ID {
+	case Bash, Zsh, POSIX, Fish:
+		return true
+	}
+	return false
+}
+
+// SupportsOS reports whether the shell is available on the given OS name
+// ("Linux", "macOS" or "Windows").
+func (s Spec) SupportsOS(osName string) bool {
+	for _, o := range s.OSes {
+		if o == osName {
+			return true
+		}
+	}
+	return false
+}
+
+var unixes = []string{"Linux", "macOS"}
+var everywhere = []string{"Linux", "macOS", "Windows"}
+
+// Specs lists the supported shells.
+var Specs = []Spec{
+	{
+		ID: Bash, Name: "bash", Program: "bash", OSes: unixes,
+		Guidance: "Use bash syntax; [[ ]], arrays, brace expansion and process substitution are available.",
+	},
+	{
+		ID: Zsh, Name: "zsh", Program: "zsh", OSes: unixes,
+		Guidance: "Use zsh syntax; recursive globs such as **/*.go are available without extra options.",
+	},
+	{
+		ID: Fish, Name: "fish", Program: "fish", OSes: unixes,
+		Guidance: "Use fish syntax, not bash: `set VAR value` (`set -x` to export) instead of VAR=value, " +
+			"`(cmd)` instead of $(cmd) or backticks, `for x in ...; ...; end` and `if ...; ...; end` blocks, " +
+			"`; and` / `; or` or && / || to chain, and no heredocs.",
+	},
+	{
+		ID: Nushell, Name: "Nushell", Program: "nu", OSes: everywhere, aliases: []string{"nushell"},
+		Guidance: "Use Nushell syntax, not POSIX: pipelines carry structured data (ls | where size > 10mb | sort-by size), " +
+			"built-ins such as ls, ps, open, where, get and each replace their POSIX namesakes and flags, " +
+			"environment variables are $env.NAME, chain commands with `;`, and prefix external programs with ^ when a built-in shares the name.",
+	},
+	{
+		ID: POSIX, Name: "POSIX sh", Program: "sh", OSes: unixes, aliases: []string{"posix", "dash", "ash"},
+		Guidance: "Use strictly POSIX sh: no [[ ]], arrays, `function` keyword, brace expansion, `source` or process substitution; " +
+			"use [ ], `.` and only portable utility flags.",
+	},
+	{
+		ID: Cmd, Name: "cmd.exe", Program: "cmd", OSes: []string{"Windows"},
+		Guidance: "Use cmd.exe syntax, not PowerShell: %VAR% variables, `&&`, `||` and `&` to chain, " +
+			"built-ins such as dir, copy, move, del, type and findstr, and `for %f in (...) do ...` loops (single % on the command line).",
+	},
+	{
+		ID: PowerShell, Name: "PowerShell", Program: "powershell", OSes: []string{"Windows"},
+		aliases: []string{"windowspowershell", "powershell5"},
+		Guidance: "Use Windows PowerShell 5.1 syntax: cmdlets and object pipelines; `&&`, `||`, the ternary operator and `??` are NOT available, " +
+			"so chain with `;` or `if ($?) { ... }`.",
+	},
+	{
+		ID: Pwsh, Name: "PowerShell 7", Program: "pwsh", OSes: everywhere, aliases: []string{"powershell7", "powershell 7"},
+		Guidance: "Use PowerShell 7 syntax: cmdlets and object pipelines; `&&`, `||`, the ternary operator and `??` are available.",
+	},
+}
+
+// Lookup finds a shell by identifier, display name, alias or path, such as
+// "fish", "PowerShell 7", "nushell" or "/usr/bin/zsh".
+func Lookup(name string) (Spec, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if strings.ContainsAny(key, `/\`) {
+		key = filepath.Base(strings.ReplaceAll(key, `\`, "/"))
+	}
+	key = strings.TrimSuffix(key, ".exe")
+	// Login shells show up as "-zsh" in process listings.
+	key = strings.TrimPrefix(key, "-")
+
+	for _, s := range Specs {
+		if key == s.ID || key == strings.ToLower(s.Name) {
+			return s, true
+		}
+		for _, a := range s.aliases {
+			if key == a {
+				return s, true
+			}
+		}
+	}
+	return Spec{}, false
+}
+
+// IDs returns the identifiers of all supported shells.
+func IDs() []string {
+	ids := make([]string, len(Specs))
+	for i, s := range Specs {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+// Detect returns the shell aiterm was started from: the parent process if
+// it is a known shell, otherwise $SHELL. ok is false if neither is known.
+func Detect() (Spec, bool) {
+	if s, ok := Lookup(parentProcessName()); ok {
+		return s, true
+	}
+	return Lookup(os.Getenv("SHELL"))
+}
+
+// parentProcessName returns the executable name of the parent process, or
+// "" where it cannot be determined cheaply.
+func parentProcessName() string {
+	ppid := os.Getppid()
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(ppid), "comm"))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	case "darwin", "freebsd", "openbsd", "netbsd":
+		out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(ppid)).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	default:
+		return ""
+	}
+}
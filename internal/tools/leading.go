@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+
+	"aiterm/internal/shell"
+)
+
+// wrappers run the command that follows them, possibly after options.
+var wrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "time": true, "nohup": true,
+	"exec": true, "command": true, "nice": true, "builtin": true,
+}
+
+// wrapperArgOptions are, per wrapper, the options that take a separate
+// argument, such as sudo -u root or nice -n 10. The same letter means
+// different things to different wrappers: time -p takes none.
+var wrapperArgOptions = map[string]map[string]bool{
+	"sudo": {
+		"-u": true, "-g": true, "-C": true, "-h": true, "-p": true,
+		"-r": true, "-t": true, "-T": true, "-U": true, "-D": true,
+	},
+	"doas": {"-u": true, "-C": true},
+	"env":  {"-u": true, "-C": true},
+	"time": {"-f": true, "-o": true},
+	"nice": {"-n": true},
+	"exec": {"-a": true},
+}
+
+// builtins are shell builtins and keywords that are never found on PATH.
+var builtins = map[string]bool{
+	"cd": true, "echo": true, "printf": true, "export": true, "set": true,
+	"unset": true, "source": true, ".": true, "alias": true, "for": true,
+	"while": true, "until": true, "if": true, "case": true, "function": true,
+	"read": true, "eval": true, "exit": true, "return": true, "test": true,
+	"[": true, "[[": true, "true": true, "false": true, "type": true,
+	"ulimit": true, "umask": true, "wait": true, "trap": true, "shift": true,
+	"local": true, "declare": true, "let": true, "pushd": true, "popd": true,
+	"dirs": true, "history": true, "jobs": true, "fg": true, "bg": true,
+	"kill": true, "!": true, "{": true, "(": true, "select": true,
+	"hash": true, "getopts": true, "pwd": true,
+}
+
+// fishBuiltins are fish builtins, keywords and shipped functions that are
+// never found on PATH, on top of the builtins shared with POSIX shells.
+var fishBuiltins = map[string]bool{
+	"string": true, "math": true, "contains": true, "argparse": true,
+	"abbr": true, "functions": true, "funced": true, "funcsave": true,
+	"status": true, "count": true, "and": true, "or": true, "not": true,
+	"begin": true, "end": true, "switch": true, "else": true, "emit": true,
+	"set_color": true, "random": true, "commandline": true, "complete": true,
+	"bind": true, "block": true, "path": true, "psub": true, "vared": true,
+	"fish_add_path": true, "prevd": true, "nextd": true, "dirh": true,
+	"cdh": true, "disown": true, "isatty": true,
+}
+
+// assignment matches a leading VAR=value environment assignment.
+var assignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// lookups are options that make a wrapper look a program up instead of
+// running it, as in command -v rg.
+var lookups = map[string]map[string]bool{
+	"command": {"-v": true, "-V": true},
+}
+
+// LeadingBinary returns the program a POSIX-style command line starts
+// with, skipping subshell and group openers, environment assignments and
+// wrappers such as sudo. It returns "" for shell builtins, lookups such
+// as command -v and empty commands.
+func LeadingBinary(command string) string {
+	fields := strings.Fields(strings.TrimLeft(command, "({ \t\n"))
+	wrapper := ""
+	for i := 0; i < len(fields); i++ {
+		field := strings.Trim(fields[i], `"'`)
+		if wrappers[field] {
+			wrapper = field
+			continue
+		}
+		if assignment.MatchString(field) {
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			if lookups[wrapper][field] {
+				return ""
+			}
+			// The wrappers' own options.
+			if wrapperArgOptions[wrapper][field] {
+				i++
+			}
+			continue
+		}
+		if builtins[field] {
+			return ""
+		}
+		return strings.TrimRight(field, ";&|")
+	}
+	return ""
+}
+
+// Missing returns the leading binary of command if it is not installed.
+// ok is false if the binary was found, is a builtin of shellType, or could
+// not be determined.
+func Missing(command, shellType string) (binary string, ok bool) {
+	binary = LeadingBinary(command)
+	if binary == "" || (shellType == shell.Fish && fishBuiltins[binary]) || Installed(binary) {
+		return "", false
+	}
+	return binary, true
+}
@@ -0,0 +1,168 @@
+// Package tools detects which common command-line tools are installed, so
+// the model can prefer the ones that exist and avoid the ones that don't.
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+// cacheFileName is the file under the config directory that remembers the
+// last probe results.
+const cacheFileName = "tools.json"
+
+// Curated lists the tools probed by default: modern replacements the model
+// likes to suggest and common utilities that are often missing.
+var Curated = []string{
+	"fd", "rg", "jq", "yq", "fzf", "bat", "eza", "tree", "ncdu", "htop",
+	"curl", "wget", "rsync", "git", "docker", "kubectl", "python3", "node",
+	"gawk", "pv", "7z", "zip", "unzip", "xclip", "pbcopy",
+}
+
+// lookPath finds installed programs; tests replace it.
+var lookPath = defaultLookPath
+
+var defaultLookPath = exec.LookPath
+
+// Availability is the result of a probe, in probe order.
+type Availability struct {
+	Available []string
+	Missing   []string
+}
+
+// Hint renders the availability as the lines added to the system prompt,
+// or "" if nothing was probed.
+func (a Availability) Hint() string {
+	var lines []string
+	if len(a.Available) > 0 {
+		lines = append(lines, "Available tools: "+strings.Join(a.Available, ", ")+".")
+	}
+	if len(a.Missing) > 0 {
+		lines = append(lines, "Not installed (do not use): "+strings.Join(a.Missing, ", ")+".")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cache is the on-disk form of earlier probe results.
+type cache struct {
+	path      string
+	CheckedAt time.Time       `json:"checked_at"`
+	Installed map[string]bool `json:"installed"`
+}
+
+// Detect probes the curated tools plus extras. Results younger than ttl
+// are taken from the cache in the config directory; only tools missing
+// from it are probed again.
+func Detect(extras []string, ttl time.Duration) Availability {
+	names := merge(Curated, extras)
+	now := time.Now()
+
+	c := loadCache()
+	if now.Sub(c.CheckedAt) >= ttl {
+		c.CheckedAt = now
+		c.Installed = map[string]bool{}
+	}
+
+	changed := false
+	var a Availability
+	for _, name := range names {
+		installed, ok := c.Installed[name]
+		if !ok {
+			installed = Installed(name)
+			c.Installed[name] = installed
+			changed = true
+		}
+		if installed {
+			a.Available = append(a.Available, name)
+		} else {
+			a.Missing = append(a.Missing, name)
+		}
+	}
+
+	if changed {
+		c.save()
+	}
+	return a
+}
+
+// Installed reports whether name is found on PATH.
+func Installed(name string) bool {
+	_, err := lookPath(name)
+	return err == nil
+}
+
+// merge returns base followed by the extras not already in it.
+func merge(base, extras []string) []string {
+	names := append([]string(nil), base...)
+	seen := map[string]bool{}
+	for _, name := range base {
+		seen[name] = true
+	}
+	for _, name := range extras {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// loadCache reads the cache file. A missing or unreadable file yields an
+// empty, expired cache; the cache is only an optimization.
+func loadCache() *cache {
+	c := &cache{Installed: map[string]bool{}}
+
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return c
+	}
+	c.path = filepath.Join(dir, cacheFileName)
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, c); err != nil || c.Installed == nil {
+		c.CheckedAt = time.Time{}
+		c.Installed = map[string]bool{}
+	}
+	return c
+}
+
+// save writes the cache back to disk, replacing the file atomically so
+// concurrent invocations never read a partial file.
+func (c *cache) save() {
+	if c.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), cacheFileName+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), c.path)
+}
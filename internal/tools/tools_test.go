@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"aiterm/internal/shell"
+)
+
+// fakePath installs a lookPath that only finds the given programs and
+// counts how often it was called.
+func fakePath(t *testing.T, installed ...string) *int {
+	calls := 0
+	lookPath = func(name string) (string, error) {
+		calls++
+		for _, n := range installed {
+			if n == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() { lookPath = defaultLookPath })
+	return &calls
+}
+
+func TestDetect_CachesWithTTL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	calls := fakePath(t, "rg", "jq", "mytool")
+
+	a := Detect([]string{"mytool", "rg"}, time.Hour)
+	if len(a.Available) != 3 || a.Available[0] != "rg" || a.Available[2] != "mytool" {
+		t.Errorf("unexpected available tools: %v", a.Available)
+	}
+	if len(a.Missing) != len(Curated)-2 {
+		t.Errorf("expected %d missing tools, got %v", len(Curated)-2, a.Missing)
+	}
+	probed := *calls
+
+	// A second run within the TTL comes from the cache.
+	Detect([]string{"mytool"}, time.Hour)
+	if *calls != probed {
+		t.Errorf("expected cached results, got %d more probes", *calls-probed)
+	}
+
+	// New extras are probed on their own.
+	Detect([]string{"mytool", "other"}, time.Hour)
+	if *calls != probed+1 {
+		t.Errorf("expected 1 probe for the new extra, got %d", *calls-probed)
+	}
+
+	// An expired cache is probed again in full.
+	Detect(nil, time.Nanosecond)
+	if *calls <= probed+1 {
+		t.Error("expected an expired cache to be probed again")
+	}
+}
+
+func TestAvailability_Hint(t *testing.T) {
+	a := Availability{Available: []string{"rg", "jq"}, Missing: []string{"fd"}}
+	want := "Available tools: rg, jq.\nNot installed (do not use): fd."
+	if got := a.Hint(); got != want {
+		t.Errorf("Hint() = %q, want %q", got, want)
+	}
+	if got := (Availability{}).Hint(); got != "" {
+		t.Errorf("expected empty hint, got %q", got)
+	}
+}
+
+func TestLeadingBinary(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"fd -e go", "fd"},
+		{"sudo apt install jq", "apt"},
+		{"sudo -u root -E rg foo", "rg"},
+		{"LANG=C sort file.txt | uniq", "sort"},
+		{"env -i FOO=1 jq . a.json", "jq"},
+		{"time make -j8", "make"},
+		{"time -p make", "make"},
+		{"time -o t.log make", "make"},
+		{"nice -n 10 tar czf a.tgz .", "tar"},
+		{"sudo -p pw: ls", "ls"},
+		{"(cd dir && make)", ""},
+		{"( cd dir && make )", ""},
+		{"(make -C dir)", "make"},
+		{"{ rg foo; echo done; }", "rg"},
+		{"command -v rg", ""},
+		{"command -V fd", ""},
+		{"command rg foo", "rg"},
+		{"type fd", ""},
+		{"hash jq", ""},
+		{"cd /tmp && ls", ""},
+		{"for f in *.go; do wc -l $f; done", ""},
+		{"/usr/local/bin/mytool --flag", "/usr/local/bin/mytool"},
+		{"ls; pwd", "ls"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := LeadingBinary(tt.command); got != tt.expected {
+			t.Errorf("LeadingBinary(%q) = %q, want %q", tt.command, got, tt.expected)
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	fakePath(t, "rg")
+
+	if bin, ok := Missing("fd -e go", shell.Bash); !ok || bin != "fd" {
+		t.Errorf("Missing(fd) = %q, %v; want fd, true", bin, ok)
+	}
+	if _, ok := Missing("rg TODO", shell.Bash); ok {
+		t.Error("rg is installed and must not be reported")
+	}
+	if _, ok := Missing("cd /tmp", shell.Bash); ok {
+		t.Error("builtins must not be reported")
+	}
+	if _, ok := Missing("string split , $x", shell.Fish); ok {
+		t.Error("fish builtins must not be reported in fish")
+	}
+	if bin, ok := Missing("string split , $x", shell.Bash); !ok || bin != "string" {
+		t.Errorf("Missing(string) in bash = %q, %v; want string, true", bin, ok)
+	}
+}
@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+// Setting is one transport setting as in effect for an endpoint. Err is
+// set when the setting cannot be used.
+type Setting struct {
+	Name  string
+	Value string
+	Err   error
+}
+
+// Describe returns the transport settings in effect for requests to
+// endpoint, for display by 'aiterm doctor'.
+func Describe(cfg *config.Config, endpoint string) []Setting {
+	tc := cfg.Transport
+	settings := []Setting{describeProxy(tc.Proxy, endpoint)}
+
+	if len(tc.CABundles) == 0 {
+		settings = append(settings, Setting{Name: "CA bundles", Value: "system roots only"})
+	}
+	for _, path := range tc.CABundles {
+		s := Setting{Name: "CA bundle"}
+		certs, err := readBundle(path)
+		if err != nil {
+			s.Value, s.Err = path, err
+		} else {
+			s.Value = fmt.Sprintf("%s (%d certificates, in addition to the system roots)", path, len(certs))
+		}
+		settings = append(settings, s)
+	}
+
+	settings = append(settings, describeClientCert(tc))
+
+	if len(tc.PinSPKI) == 0 {
+		settings = append(settings, Setting{Name: "SPKI pins", Value: "none"})
+	}
+	pinHost := PinnedHost(endpoint)
+	if pinHost == "" {
+		pinHost = "IP address endpoints"
+	}
+	for _, pin := range tc.PinSPKI {
+		s := Setting{Name: "SPKI pin", Value: pin + " (for " + pinHost + ")"}
+		_, s.Err = config.ParsePin(pin)
+		settings = append(settings, s)
+	}
+
+	if len(cfg.Headers) == 0 {
+		settings = append(settings, Setting{Name: "Extra headers", Value: "none"})
+	}
+	for _, name := range sortedKeys(cfg.Headers) {
+		settings = append(settings, Setting{Name: "Header", Value: name + ": " + config.MaskToken(cfg.Headers[name])})
+	}
+	return settings
+}
+
+// describeProxy reports the proxy used for requests to endpoint.
+func describeProxy(value, endpoint string) Setting {
+	s := Setting{Name: "Proxy"}
+	proxy, err := proxyFunc(value)
+	if err != nil {
+		s.Value, s.Err = value, err
+		return s
+	}
+	if proxy == nil {
+		s.Value = "none (transport.proxy is none)"
+		return s
+	}
+
+	source := "transport.proxy"
+	if value == "" {
+		source = "environment"
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"} {
+			if v := os.Getenv(name); v != "" {
+				source += ", " + name + "=" + redactURL(v)
+			}
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+	if err != nil {
+		s.Value, s.Err = source, fmt.Errorf("invalid endpoint: %w", err)
+		return s
+	}
+	u, err := proxy(req)
+	switch {
+	case err != nil:
+		s.Value, s.Err = source, err
+	case u == nil:
+		s.Value = "direct (" + source + ")"
+	default:
+		s.Value = u.Redacted() + " (" + source + ")"
+	}
+	return s
+}
+
+// describeClientCert reports the client certificate for mutual TLS.
+func describeClientCert(tc config.TransportConfig) Setting {
+	s := Setting{Name: "Client certificate"}
+	if tc.ClientCert == "" && tc.ClientKey == "" {
+		s.Value = "none"
+		return s
+	}
+	cert, err := loadClientCert(tc)
+	if err != nil {
+		s.Value, s.Err = tc.ClientCert, err
+		return s
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		s.Value, s.Err = tc.ClientCert, err
+		return s
+	}
+	s.Value = fmt.Sprintf("%s (%s, expires %s)", tc.ClientCert, leaf.Subject.String(), leaf.NotAfter.Format("2006-01-02"))
+	if time.Now().After(leaf.NotAfter) {
+		s.Err = fmt.Errorf("the certificate expired on %s", leaf.NotAfter.Format("2006-01-02"))
+	}
+	return s
+}
+
+// redactURL hides the password of a proxy URL from the environment.
+func redactURL(raw string) string {
+	if !strings.Contains(raw, "@") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(set)"
+	}
+	return u.Redacted()
+}
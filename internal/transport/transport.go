@@ -0,0 +1,235 @@
+// Package transport builds the HTTP transport for API requests from the
+// transport settings: proxy, extra trusted CAs, a client certificate for
+// mutual TLS, SPKI pinning and extra headers.
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"aiterm/internal/config"
+	"aiterm/internal/prompt"
+)
+
+// New returns the transport for API requests configured by cfg. It fails
+// when a CA bundle, the client certificate or a pin cannot be used.
+func New(cfg *config.Config) (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	// connect_timeout covers the TCP connection, to the proxy if any, and
+	// the TLS handshake.
+	connect := cfg.ConnectTimeoutDuration()
+	t.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = connect
+
+	proxy, err := proxyFunc(cfg.Transport.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	t.Proxy = proxy
+
+	tlsConfig, err := tlsConfig(cfg.Transport, PinnedHost(cfg.APIEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	t.TLSClientConfig = tlsConfig
+
+	if len(cfg.Headers) == 0 {
+		return t, nil
+	}
+	return &headerTransport{next: t, headers: cfg.Headers}, nil
+}
+
+// proxyFunc returns the proxy selection for a transport.proxy value.
+func proxyFunc(value string) (func(*http.Request) (*url.URL, error), error) {
+	if err := config.CheckProxy(value); err != nil {
+		return nil, err
+	}
+	switch {
+	case value == "":
+		return http.ProxyFromEnvironment, nil
+	case strings.EqualFold(value, config.ProxyNone):
+		return nil, nil
+	}
+	u, _ := url.Parse(value)
+	return http.ProxyURL(u), nil
+}
+
+// PinnedHost returns the host the SPKI pins apply to: that of the primary
+// endpoint, as it appears in the TLS server name. Hosts given as IP
+// addresses send no server name, so they are returned as "".
+func PinnedHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || net.ParseIP(u.Hostname()) != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// tlsConfig returns the TLS settings for tc, or nil when the defaults do.
+// Pins are only checked for connections to pinHost.
+func tlsConfig(tc config.TransportConfig, pinHost string) (*tls.Config, error) {
+	if len(tc.CABundles) == 0 && tc.ClientCert == "" && tc.ClientKey == "" && len(tc.PinSPKI) == 0 {
+		return nil, nil
+	}
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(tc.CABundles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range tc.CABundles {
+			if _, err := addBundle(pool, path); err != nil {
+				return nil, err
+			}
+		}
+		conf.RootCAs = pool
+	}
+
+	if tc.ClientCert != "" || tc.ClientKey != "" {
+		cert, err := loadClientCert(tc)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(tc.PinSPKI) > 0 {
+		pins := make([][]byte, len(tc.PinSPKI))
+		for i, pin := range tc.PinSPKI {
+			hash, err := config.ParsePin(pin)
+			if err != nil {
+				return nil, err
+			}
+			pins[i] = hash
+		}
+		// Runs after the usual chain verification, which still applies.
+		// Failover endpoints on other hosts and an https:// proxy share
+		// the transport, but are not pinned.
+		conf.VerifyConnection = func(cs tls.ConnectionState) error {
+			if !strings.EqualFold(cs.ServerName, pinHost) {
+				return nil
+			}
+			for _, cert := range cs.PeerCertificates {
+				if matchesPin(cert, pins) {
+					return nil
+				}
+			}
+			return fmt.Errorf("certificate of %s matches none of the pinned keys (transport.pin_spki)", cs.ServerName)
+		}
+	}
+	return conf, nil
+}
+
+// addBundle adds the certificates of a PEM file to pool and returns how
+// many there were.
+func addBundle(pool *x509.CertPool, path string) (int, error) {
+	certs, err := readBundle(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return len(certs), nil
+}
+
+// readBundle parses the certificates of a PEM file.
+func readBundle(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(prompt.ExpandPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("CA bundle %s: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+	return certs, nil
+}
+
+// loadClientCert loads the client certificate and key for mutual TLS.
+func loadClientCert(tc config.TransportConfig) (tls.Certificate, error) {
+	if tc.ClientCert == "" || tc.ClientKey == "" {
+		return tls.Certificate{}, fmt.Errorf("transport.client_cert and transport.client_key must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(prompt.ExpandPath(tc.ClientCert), prompt.ExpandPath(tc.ClientKey))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// Pin returns the SPKI pin of cert, as "sha256/<base64>".
+func Pin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// matchesPin reports whether the public key of cert has one of the pinned
+// hashes.
+func matchesPin(cert *x509.Certificate, pins [][]byte) bool {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if bytes.Equal(hash[:], pin) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerTransport adds extra headers to every request. Headers the
+// request already has, such as the credentials of the provider, win.
+type headerTransport struct {
+	next    http.RoundTripper
+	headers map[string]string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
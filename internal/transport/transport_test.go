@@ -0,0 +1,178 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"aiterm/internal/config"
+)
+
+// get sends a GET request to url through a transport built from cfg.
+func get(t *testing.T, cfg *config.Config, url string) (*http.Response, error) {
+	t.Helper()
+	rt, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "Bearer sk-test")
+	resp, err := (&http.Client{Transport: rt, Timeout: 5 * time.Second}).Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+// writePEM writes a PEM block to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNew_CABundleAndPin(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	bundle := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	// The test server's certificate is not trusted by default.
+	cfg := config.DefaultConfig()
+	if _, err := get(t, cfg, server.URL); err == nil {
+		t.Fatal("expected an untrusted certificate to fail")
+	}
+
+	cfg.Transport.CABundles = []string{bundle}
+	if _, err := get(t, cfg, server.URL); err != nil {
+		t.Fatalf("expected the CA bundle to be trusted: %v", err)
+	}
+
+	cfg.APIEndpoint = server.URL + "/v1/chat/completions"
+	cfg.Transport.PinSPKI = []string{Pin(server.Certificate())}
+	if _, err := get(t, cfg, server.URL); err != nil {
+		t.Fatalf("expected the pinned key to be accepted: %v", err)
+	}
+
+	cfg.Transport.PinSPKI = []string{"sha256/" + strings.Repeat("A", 43) + "="}
+	if _, err := get(t, cfg, server.URL); err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Errorf("expected a pin mismatch, got %v", err)
+	}
+
+	// Pins belong to the primary endpoint's host; others, such as failover
+	// endpoints, are only verified as usual.
+	cfg.APIEndpoint = "https://api.example.com/v1/chat/completions"
+	if _, err := get(t, cfg, server.URL); err != nil {
+		t.Errorf("expected another host not to be pinned: %v", err)
+	}
+}
+
+func TestNew_ClientCert(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Transport.CABundles = []string{writePEM(t, dir, "ca.pem", "CERTIFICATE", server.Certificate().Raw)}
+	if _, err := get(t, cfg, server.URL); err == nil {
+		t.Fatal("expected the server to require a client certificate")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "aiterm-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Transport.ClientCert = writePEM(t, dir, "client.pem", "CERTIFICATE", der)
+	cfg.Transport.ClientKey = writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+
+	if _, err := get(t, cfg, server.URL); err != nil {
+		t.Fatalf("expected mutual TLS to succeed: %v", err)
+	}
+
+	s := describeClientCert(cfg.Transport)
+	if s.Err != nil || !strings.Contains(s.Value, "CN=aiterm-test") {
+		t.Errorf("unexpected description %+v", s)
+	}
+}
+
+func TestNew_ProxyAndHeaders(t *testing.T) {
+	var gotURL, gotTeam, gotAuth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL, gotTeam, gotAuth = r.URL.String(), r.Header.Get("X-Team"), r.Header.Get("Authorization")
+	}))
+	defer proxy.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Transport.Proxy = proxy.URL
+	cfg.Headers = map[string]string{"X-Team": "platform", "Authorization": "Bearer other"}
+	if _, err := get(t, cfg, "http://api.invalid/v1/chat/completions"); err != nil {
+		t.Fatalf("request through the proxy failed: %v", err)
+	}
+	if gotURL != "http://api.invalid/v1/chat/completions" {
+		t.Errorf("proxy received %q", gotURL)
+	}
+	// Extra headers are added, but never replace the request's own.
+	if gotTeam != "platform" || gotAuth != "Bearer sk-test" {
+		t.Errorf("unexpected headers X-Team=%q Authorization=%q", gotTeam, gotAuth)
+	}
+
+	if s := describeProxy(proxy.URL, "http://api.invalid/v1"); s.Err != nil || !strings.HasPrefix(s.Value, proxy.URL) {
+		t.Errorf("unexpected proxy description %+v", s)
+	}
+	if s := describeProxy("none", "http://api.invalid/v1"); !strings.HasPrefix(s.Value, "none") {
+		t.Errorf("unexpected proxy description %+v", s)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "empty.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+
+	tests := map[string]config.TransportConfig{
+		"missing bundle": {CABundles: []string{filepath.Join(dir, "missing.pem")}},
+		"empty bundle":   {CABundles: []string{notPEM}},
+		"cert only":      {ClientCert: notPEM},
+		"bad key pair":   {ClientCert: notPEM, ClientKey: notPEM},
+		"bad pin":        {PinSPKI: []string{"sha256/short"}},
+		"bad proxy":      {Proxy: "ftp://proxy:21"},
+	}
+	for name, tc := range tests {
+		cfg := config.DefaultConfig()
+		cfg.Transport = tc
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}